@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"curl-translation/tool"
+)
+
+// This program is the Go translation of curl-src/src/tool_main.c. As in C,
+// main only sets up the global state and hands the command line over to
+// the tool's `operate` function, which does the actual work.
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+
+	global := tool.NewGlobalConfig()
+	err := tool.Operate(ctx, global, os.Args[1:])
+	stop()
+	os.Exit(int(tool.ErrorCode(err)))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCurlMain(t *testing.T) {
+	// --- Setup: Build the binary and start a server to fetch from ---
+	tempDir := t.TempDir()
+	binaryPath := filepath.Join(tempDir, "curl")
+	buildCmd := exec.Command("go", "build", "-o", binaryPath, ".")
+	if err := buildCmd.Run(); err != nil {
+		t.Fatalf("Failed to build binary: %v", err)
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.URL.Path == "/slow" {
+			time.Sleep(time.Second)
+		}
+		fmt.Fprint(w, "hello")
+	}))
+	defer srv.Close()
+
+	// Keep any .curlrc of the user running the tests out of the way.
+	env := append(os.Environ(), "CURL_HOME="+tempDir, "HOME="+tempDir, "XDG_CONFIG_HOME="+tempDir)
+
+	// --- Test Cases ---
+	testCases := []struct {
+		name         string
+		args         []string
+		expectOutput string
+		expectCode   int
+	}{
+		{
+			name:         "fetch to stdout",
+			args:         []string{srv.URL + "/"},
+			expectOutput: "hello",
+		},
+		{
+			name:         "write-out",
+			args:         []string{"-s", "-o", filepath.Join(tempDir, "out"), "-w", "%{http_code}", srv.URL + "/"},
+			expectOutput: "200",
+		},
+		{
+			name:       "fail on HTTP error",
+			args:       []string{"-sf", srv.URL + "/missing"},
+			expectCode: 22,
+		},
+		{
+			name:       "max-time timeout",
+			args:       []string{"-s", "-m", "0.2", srv.URL + "/slow"},
+			expectCode: 28,
+		},
+		{
+			name:       "unknown option",
+			args:       []string{"--no-such-option", srv.URL + "/"},
+			expectCode: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd := exec.Command(binaryPath, tc.args...)
+			cmd.Env = env
+			output, _ := cmd.Output()
+			if code := cmd.ProcessState.ExitCode(); code != tc.expectCode {
+				t.Fatalf("Running %v: exit code = %d; want %d", tc.args, code, tc.expectCode)
+			}
+			if string(output) != tc.expectOutput {
+				t.Errorf("Output = %q; want %q", string(output), tc.expectOutput)
+			}
+		})
+	}
+}
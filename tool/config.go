@@ -1,6 +1,10 @@
 package tool
 
-import "time"
+import (
+	"io"
+	"os"
+	"time"
+)
 
 // This file contains the Go translation of the central `OperationConfig`
 // and `GlobalConfig` structs from `curl-src/src/tool_cfgable.h`.
@@ -38,15 +42,28 @@ type OperationConfig struct {
 	WriteOut          string
 	Range             string
 	CustomRequest     string
+	Query             string // --url-query parts, joined with '&'
 
 	// Slices of strings
 	Headers []string
 
+	// Form holds the parts added with -F and --form-string.
+	Form []*FormPart
+	// FormNesting holds the multiparts opened with "-F name=(" and not
+	// closed yet with "-F =)". New parts go into the last one. It plays
+	// the role of the C `mimecurrent` field.
+	FormNesting []*FormPart
+
 	// Numeric options
 	MaxRedirs      int64
-	AuthType       uint // Bitmask
+	Segments       int64 // --segments, the parallel range requests of a download
+	AuthType       uint  // Bitmask
 	FollowLocation bool
 
+	// HTTPReq is the request method implied by the options, such as a
+	// POST for -d. It is the C `httpreq` field.
+	HTTPReq HTTPRequestManager
+
 	// Boolean options
 	InsecureOK         bool
 	ShowHeaders        bool
@@ -55,10 +72,21 @@ type OperationConfig struct {
 	ContentDisposition bool
 	RemoteTime         bool
 	FailOnError        bool
+	JSON               bool // --json
+	FormEscape         bool // --form-escape
+	SparseRanges       bool // --sparse-ranges
+	GlobOff            bool // -g, --globoff
 	UseResume          bool
 
+	// ResumeFrom is the offset to resume the transfer at, given with
+	// -C/--continue-at. With ResumeFromCurrent ("-C -") it is taken from
+	// the size of the output file instead.
+	ResumeFrom        int64
+	ResumeFromCurrent bool
+
 	// Timeouts
 	ConnectTimeout time.Duration
+	Timeout        time.Duration // --max-time
 
 	// URL List
 	URLList []*URLConfig
@@ -68,13 +96,18 @@ type OperationConfig struct {
 	Prev *OperationConfig
 }
 
+// defaultMaxRedirs is the C `DEFAULT_MAXREDIRS`, the redirect limit used
+// with --location when --max-redirs is not given.
+const defaultMaxRedirs = 50
+
 // NewOperationConfig creates and returns a new, initialized OperationConfig.
 // This is the Go equivalent of the C function `config_alloc`.
 func NewOperationConfig() *OperationConfig {
 	return &OperationConfig{
 		// Initialize fields with their default zero values, which is often correct.
 		// Specific defaults can be set here if needed.
-		URLList: make([]*URLConfig, 0),
+		URLList:   make([]*URLConfig, 0),
+		MaxRedirs: defaultMaxRedirs,
 	}
 }
 
@@ -83,13 +116,35 @@ func NewOperationConfig() *OperationConfig {
 type GlobalConfig struct {
 	First *OperationConfig
 	Last  *OperationConfig
+
+	Silent    bool // --silent
+	ShowError bool // --show-error
+	Verbose   bool // --verbose
+	FailEarly bool // --fail-early
+
+	Parallel        bool // --parallel
+	ParallelMax     int  // --parallel-max
+	ParallelConnect bool // --parallel-immediate
+
+	// Variables holds the variables set with --variable, by name.
+	Variables map[string]string
+
+	// Stdout and Stderr are the streams the tool writes to. They are the
+	// equivalent of `tool_stdout` and `tool_stderr` in C and can be replaced
+	// to capture the output of a run.
+	Stdout io.Writer
+	Stderr io.Writer
 	// Other global fields like TraceDump, LibCurl, etc., will be added here as needed.
 }
 
 // NewGlobalConfig creates a new GlobalConfig, initializes it, and sets up
 // the first OperationConfig. This is the Go equivalent of `globalconf_init`.
 func NewGlobalConfig() *GlobalConfig {
-	g := &GlobalConfig{}
+	g := &GlobalConfig{
+		ParallelMax: parallelDefault,
+		Stdout:      os.Stdout,
+		Stderr:      os.Stderr,
+	}
 	first := NewOperationConfig()
 	g.First = first
 	g.Last = first
@@ -99,4 +154,4 @@ func NewGlobalConfig() *GlobalConfig {
 // Note: The C file `tool_cfgable.c` contains `config_free` and
 // `free_config_fields`. These are not needed in Go because the garbage
 // collector automatically handles deallocation when the structs are no longer
-// referenced.
\ No newline at end of file
+// referenced.
@@ -0,0 +1,172 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CurlCode is a translation of libcurl's `CURLcode` enum from
+// curl-src/include/curl/curl.h. Only the codes the Go transfer engine can
+// produce are listed. The numeric values are kept identical to the C ones
+// because the tool uses them as its exit status.
+type CurlCode int
+
+const (
+	CurlOK                     CurlCode = 0
+	CurlUnsupportedProtocol    CurlCode = 1
+	CurlFailedInit             CurlCode = 2
+	CurlURLMalformat           CurlCode = 3
+	CurlNotBuiltIn             CurlCode = 4
+	CurlCouldntResolveProxy    CurlCode = 5
+	CurlCouldntResolveHost     CurlCode = 6
+	CurlCouldntConnect         CurlCode = 7
+	CurlWeirdServerReply       CurlCode = 8
+	CurlRemoteAccessDenied     CurlCode = 9
+	CurlFTPWeirdPasvReply      CurlCode = 13
+	CurlFTPCouldntSetType      CurlCode = 17
+	CurlPartialFile            CurlCode = 18
+	CurlFTPCouldntRetrFile     CurlCode = 19
+	CurlHTTPReturnedError      CurlCode = 22
+	CurlWriteError             CurlCode = 23
+	CurlUploadFailed           CurlCode = 25
+	CurlReadError              CurlCode = 26
+	CurlOutOfMemory            CurlCode = 27
+	CurlOperationTimedOut      CurlCode = 28
+	CurlFTPCouldntUseRest      CurlCode = 31
+	CurlRangeError             CurlCode = 33
+	CurlSSLConnectError        CurlCode = 35
+	CurlBadDownloadResume      CurlCode = 36
+	CurlFileCouldntReadFile    CurlCode = 37
+	CurlAbortedByCallback      CurlCode = 42
+	CurlBadFunctionArgument    CurlCode = 43
+	CurlTooManyRedirects       CurlCode = 47
+	CurlGotNothing             CurlCode = 52
+	CurlSendError              CurlCode = 55
+	CurlRecvError              CurlCode = 56
+	CurlPeerFailedVerification CurlCode = 60
+	CurlBadContentEncoding     CurlCode = 61
+	CurlLoginDenied            CurlCode = 67
+	CurlRemoteFileNotFound     CurlCode = 78
+)
+
+// String returns the generic description of a code. It is the Go
+// equivalent of `curl_easy_strerror` from curl-src/lib/strerror.c.
+func (c CurlCode) String() string {
+	switch c {
+	case CurlOK:
+		return "No error"
+	case CurlUnsupportedProtocol:
+		return "Unsupported protocol"
+	case CurlFailedInit:
+		return "Failed initialization"
+	case CurlURLMalformat:
+		return "URL using bad/illegal format or missing URL"
+	case CurlNotBuiltIn:
+		return "A requested feature, protocol or option was not found built-in in this libcurl due to a build-time decision."
+	case CurlCouldntResolveProxy:
+		return "Could not resolve proxy name"
+	case CurlCouldntResolveHost:
+		return "Could not resolve hostname"
+	case CurlCouldntConnect:
+		return "Could not connect to server"
+	case CurlWeirdServerReply:
+		return "Weird server reply"
+	case CurlRemoteAccessDenied:
+		return "Access denied to remote resource"
+	case CurlFTPWeirdPasvReply:
+		return "FTP: unknown PASV reply"
+	case CurlFTPCouldntSetType:
+		return "FTP: could not set file type"
+	case CurlPartialFile:
+		return "Transferred a partial file"
+	case CurlFTPCouldntRetrFile:
+		return "FTP: could not retrieve (RETR failed) the specified file"
+	case CurlHTTPReturnedError:
+		return "HTTP response code said error"
+	case CurlWriteError:
+		return "Failed writing received data to disk/application"
+	case CurlUploadFailed:
+		return "Upload failed (at start/before it took off)"
+	case CurlReadError:
+		return "Failed to open/read local data from file/application"
+	case CurlOutOfMemory:
+		return "Out of memory"
+	case CurlOperationTimedOut:
+		return "Timeout was reached"
+	case CurlFTPCouldntUseRest:
+		return "FTP: command REST failed"
+	case CurlRangeError:
+		return "Requested range was not delivered by the server"
+	case CurlSSLConnectError:
+		return "SSL connect error"
+	case CurlBadDownloadResume:
+		return "Could not resume download"
+	case CurlFileCouldntReadFile:
+		return "Could not read a file:// file"
+	case CurlAbortedByCallback:
+		return "Operation was aborted by an application callback"
+	case CurlBadFunctionArgument:
+		return "A libcurl function was given a bad argument"
+	case CurlTooManyRedirects:
+		return "Number of redirects hit maximum amount"
+	case CurlGotNothing:
+		return "Server returned nothing (no headers, no data)"
+	case CurlSendError:
+		return "Failed sending data to the peer"
+	case CurlRecvError:
+		return "Failure when receiving data from the peer"
+	case CurlPeerFailedVerification:
+		return "SSL peer certificate or SSH remote key was not OK"
+	case CurlBadContentEncoding:
+		return "Unrecognized or bad HTTP Content or Transfer-Encoding"
+	case CurlLoginDenied:
+		return "Login denied"
+	case CurlRemoteFileNotFound:
+		return "Remote file not found"
+	default:
+		return "Unknown error"
+	}
+}
+
+// TransferError is the error returned by a failed transfer. It pairs the
+// result code with the detailed message libcurl would leave in its
+// CURLOPT_ERRORBUFFER. Its Error string is the "(N) message" part of the
+// tool's "curl: (N) message" output, the "curl: " prefix being added by
+// Messager.Errorf.
+type TransferError struct {
+	Code    CurlCode
+	Message string
+}
+
+// newTransferError creates a TransferError with a formatted message.
+func newTransferError(code CurlCode, format string, args ...interface{}) *TransferError {
+	return &TransferError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *TransferError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = e.Code.String()
+	}
+	return fmt.Sprintf("(%d) %s", e.Code, msg)
+}
+
+// ErrorCode returns the exit status that corresponds to err. A nil error is
+// CurlOK, a TransferError carries its own code, an option that could not
+// read its file is CurlReadError, and any other error is a setup failure,
+// which curl reports as CURLE_FAILED_INIT.
+func ErrorCode(err error) CurlCode {
+	if err == nil {
+		return CurlOK
+	}
+	var te *TransferError
+	if errors.As(err, &te) {
+		return te.Code
+	}
+	if errors.Is(err, ParamReadError) {
+		// As in curl, an option that fails to read its file is a read
+		// error rather than a failed initialization.
+		return CurlReadError
+	}
+	return CurlFailedInit
+}
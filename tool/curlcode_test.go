@@ -0,0 +1,59 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestCurlCode_String(t *testing.T) {
+	testCases := []struct {
+		code     CurlCode
+		expected string
+	}{
+		{CurlOK, "No error"},
+		{CurlCouldntResolveHost, "Could not resolve hostname"},
+		{CurlOperationTimedOut, "Timeout was reached"},
+		{CurlCode(9999), "Unknown error"},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.code.String(); got != tc.expected {
+			t.Errorf("CurlCode(%d).String() = %q; want %q", tc.code, got, tc.expected)
+		}
+	}
+}
+
+func TestTransferError(t *testing.T) {
+	err := newTransferError(CurlCouldntResolveHost, "Could not resolve host: %s", "example.invalid")
+	if got := err.Error(); got != "(6) Could not resolve host: example.invalid" {
+		t.Errorf("Error() = %q", got)
+	}
+
+	// Without a detailed message, the generic description is used.
+	err = &TransferError{Code: CurlSSLConnectError}
+	if got := err.Error(); got != "(35) SSL connect error" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		expected CurlCode
+	}{
+		{"nil", nil, CurlOK},
+		{"transfer error", &TransferError{Code: CurlTooManyRedirects}, CurlTooManyRedirects},
+		{"wrapped transfer error", fmt.Errorf("wrap: %w", &TransferError{Code: CurlWriteError}), CurlWriteError},
+		{"other error", errors.New("boom"), CurlFailedInit},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ErrorCode(tc.err); got != tc.expected {
+				t.Errorf("ErrorCode() = %d; want %d", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Easy is a handle for performing transfers from Go code, modelled on
+// libcurl's easy interface (`curl_easy_setopt`, `curl_easy_perform` and
+// `curl_easy_getinfo`). It wraps an OperationConfig so that programs get the
+// same knobs as the command line tool: options are set by their long
+// command line names, and the information gathered by a transfer is read
+// back by the names of the --write-out variables.
+//
+// An Easy handle performs one URL at a time and is not safe for concurrent
+// use. It can be reused for several transfers; options stay set between
+// calls to Perform until Reset is called.
+type Easy struct {
+	global *GlobalConfig
+	parser *ParameterParser
+	info   map[string]interface{}
+}
+
+// NewEasy creates a new handle with default options. This is the Go
+// equivalent of `curl_easy_init`.
+func NewEasy() *Easy {
+	e := &Easy{}
+	e.Reset()
+	return e
+}
+
+// Reset restores every option to its default value, keeping the output
+// streams. This is the Go equivalent of `curl_easy_reset`.
+func (e *Easy) Reset() {
+	global := NewGlobalConfig()
+	if e.global != nil {
+		global.Stdout = e.global.Stdout
+		global.Stderr = e.global.Stderr
+	}
+	e.global = global
+	e.parser = NewParameterParser(global)
+	e.info = nil
+}
+
+// SetOutput sets the writer that receives the response body when no
+// output file is set. It defaults to os.Stdout, like libcurl's default
+// write callback.
+func (e *Easy) SetOutput(w io.Writer) {
+	e.global.Stdout = w
+}
+
+// SetErrorOutput sets the writer used for warnings and the --verbose
+// trace. It defaults to os.Stderr.
+func (e *Easy) SetErrorOutput(w io.Writer) {
+	e.global.Stderr = w
+}
+
+// Config returns the operation config the handle applies its options to.
+func (e *Easy) Config() *OperationConfig {
+	return e.global.Last
+}
+
+// SetOpt sets an option by its long command line name, with or without the
+// leading dashes, e.g. SetOpt("user-agent", "agent/1.0") or
+// SetOpt("--header", "X-Test: 1"). Boolean options ignore value. Setting
+// "url" replaces the URL of the handle instead of adding another one. As
+// the handle performs a single URL, an option that would pair with a
+// second URL, such as a second "output", fails with
+// CurlBadFunctionArgument and is not set.
+func (e *Easy) SetOpt(option, value string) error {
+	name := strings.TrimLeft(option, "-")
+	config := e.global.Last
+	if name == "url" {
+		if len(config.URLList) == 0 {
+			config.URLList = append(config.URLList, &URLConfig{IsSet: true})
+		}
+		config.URLList[0].URL = value
+		return nil
+	}
+	if _, err := e.parser.ParseOne("--"+name, value); err != nil {
+		return err
+	}
+	if len(config.URLList) > 1 {
+		config.URLList = config.URLList[:1]
+		return newTransferError(CurlBadFunctionArgument, "Option %s is already set on the handle", name)
+	}
+	return nil
+}
+
+// Perform runs a transfer of the URL set on the handle. It is the Go
+// equivalent of `curl_easy_perform`. A failed transfer returns a
+// *TransferError, whose Code matches the CURLcode libcurl would return.
+func (e *Easy) Perform(ctx context.Context) error {
+	config := e.global.Last
+	if len(config.URLList) == 0 || config.URLList[0].URL == "" {
+		return newTransferError(CurlURLMalformat, "No URL set")
+	}
+	u := config.URLList[0]
+
+	rawURL := u.URL
+	if u.Infile != "" && !stdinUpload(u.Infile) {
+		rawURL = addFileNameToURL(rawURL, u.Infile)
+	}
+	t := NewTransfer(e.global, config, transferURL(config, rawURL), u.Outfile)
+	t.UseRemote = u.UseRemote
+	t.Infile = u.Infile
+	err := t.Perform(ctx)
+	e.info = t.Info
+	return err
+}
+
+// GetInfo returns a piece of information about the last transfer, named
+// like the --write-out variables ("http_code", "time_connect",
+// "size_download", "remote_ip", ...). It is the Go equivalent of
+// `curl_easy_getinfo`. Numeric values are int64, times are float64 seconds
+// and the rest are strings. Information the transfer did not produce is
+// returned as the zero value of its type.
+func (e *Easy) GetInfo(name string) (interface{}, error) {
+	v, ok := variables[name]
+	if !ok || v.Type == VarTypeJSON || v.Type == VarTypeSpecial {
+		return nil, fmt.Errorf("unknown info %q", name)
+	}
+	if e.info == nil {
+		return nil, fmt.Errorf("no transfer has been performed")
+	}
+	if val, ok := e.info[name]; ok {
+		return val, nil
+	}
+	switch v.Type {
+	case VarTypeLong, VarTypeOffset:
+		return int64(0), nil
+	case VarTypeTime:
+		return float64(0), nil
+	default:
+		return "", nil
+	}
+}
@@ -0,0 +1,129 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestEasy(t *testing.T) {
+	srv := newTestServer(t)
+
+	t.Run("perform and get info", func(t *testing.T) {
+		var body bytes.Buffer
+		e := NewEasy()
+		e.SetOutput(&body)
+		for opt, val := range map[string]string{
+			"url":        srv.URL + "/redirect",
+			"--location": "",
+			"user-agent": "easy/1.0",
+		} {
+			if err := e.SetOpt(opt, val); err != nil {
+				t.Fatalf("SetOpt(%q) failed: %v", opt, err)
+			}
+		}
+
+		if err := e.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		if body.String() != "hello world" {
+			t.Errorf("body = %q; want %q", body.String(), "hello world")
+		}
+
+		checks := map[string]interface{}{
+			"http_code":     int64(200),
+			"num_redirects": int64(1),
+			"size_download": int64(11),
+			"url_effective": srv.URL + "/hello",
+			"remote_ip":     "127.0.0.1",
+			"redirect_url":  "",
+		}
+		for name, want := range checks {
+			got, err := e.GetInfo(name)
+			if err != nil {
+				t.Fatalf("GetInfo(%q) failed: %v", name, err)
+			}
+			if got != want {
+				t.Errorf("GetInfo(%q) = %v (%T); want %v (%T)", name, got, got, want, want)
+			}
+		}
+		if v, _ := e.GetInfo("time_total"); v.(float64) <= 0 {
+			t.Errorf("GetInfo(time_total) = %v; want a positive time", v)
+		}
+	})
+
+	t.Run("url replaces previous url", func(t *testing.T) {
+		var body bytes.Buffer
+		e := NewEasy()
+		e.SetOutput(&body)
+		e.SetOpt("url", srv.URL+"/missing")
+		e.SetOpt("url", srv.URL+"/hello")
+		if err := e.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		if len(e.Config().URLList) != 1 || body.String() != "hello world" {
+			t.Errorf("URLList = %d entries, body = %q", len(e.Config().URLList), body.String())
+		}
+	})
+
+	t.Run("transfer error", func(t *testing.T) {
+		var body bytes.Buffer
+		e := NewEasy()
+		e.SetOutput(&body)
+		e.SetOpt("url", srv.URL+"/missing")
+		e.SetOpt("fail", "")
+		err := e.Perform(context.Background())
+		var te *TransferError
+		if !errors.As(err, &te) || te.Code != CurlHTTPReturnedError {
+			t.Fatalf("Perform() error = %v; want code %d", err, CurlHTTPReturnedError)
+		}
+		if code, _ := e.GetInfo("http_code"); code != int64(404) {
+			t.Errorf("GetInfo(http_code) = %v; want 404", code)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		e := NewEasy()
+		if err := e.SetOpt("no-such-option", "x"); err == nil {
+			t.Error("SetOpt() should fail for an unknown option")
+		}
+		if _, err := e.GetInfo("http_code"); err == nil {
+			t.Error("GetInfo() should fail before Perform()")
+		}
+		if err := e.Perform(context.Background()); ErrorCode(err) != CurlURLMalformat {
+			t.Errorf("Perform() without URL error = %v; want code %d", err, CurlURLMalformat)
+		}
+		if _, err := e.GetInfo("nonsense"); err == nil {
+			t.Error("GetInfo() should fail for an unknown name")
+		}
+	})
+
+	t.Run("one url node", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		e := NewEasy()
+		if err := e.SetOpt("output", out); err != nil {
+			t.Fatalf("SetOpt(output) failed: %v", err)
+		}
+		if err := e.SetOpt("output", out+".2"); ErrorCode(err) != CurlBadFunctionArgument {
+			t.Errorf("second SetOpt(output) error = %v; want code %d", err, CurlBadFunctionArgument)
+		}
+		if err := e.SetOpt("upload-file", out); err != nil {
+			t.Errorf("SetOpt(upload-file) failed: %v", err)
+		}
+		e.SetOpt("url", srv.URL+"/hello")
+		if n := len(e.Config().URLList); n != 1 || e.Config().URLList[0].Outfile != out {
+			t.Fatalf("URLList = %d entries; want 1 with output %q", n, out)
+		}
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		e := NewEasy()
+		e.SetOpt("user-agent", "x")
+		e.Reset()
+		if e.Config().UserAgent != "" {
+			t.Errorf("UserAgent = %q after Reset(); want empty", e.Config().UserAgent)
+		}
+	})
+}
@@ -0,0 +1,86 @@
+package tool
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// This file contains the file:// transfers, which libcurl implements in
+// lib/file.c: the local file, or the names in a directory, is copied to the
+// output, honoring -r and -C.
+
+// performFile transfers the local file named by the file:// URL u.
+func (t *Transfer) performFile(u *url.URL) *TransferError {
+	switch u.Hostname() {
+	case "", "localhost", "127.0.0.1":
+	default:
+		return newTransferError(CurlURLMalformat, "file:// URLs with a host name are not supported")
+	}
+	t.Info["url_effective"] = t.URL
+	t.Info["scheme"] = "file"
+
+	file, err := os.Open(u.Path)
+	if err != nil {
+		return newTransferError(CurlFileCouldntReadFile, "Could not open file %s", u.Path)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return newTransferError(CurlFileCouldntReadFile, "Could not open file %s", u.Path)
+	}
+
+	var content io.ReaderAt = file
+	size := info.Size()
+	if info.IsDir() {
+		// A directory is transferred as the list of its entries.
+		names, err := file.Readdirnames(-1)
+		if err != nil {
+			return newTransferError(CurlFileCouldntReadFile, "Could not open file %s", u.Path)
+		}
+		sort.Strings(names)
+		var listing strings.Builder
+		for _, name := range names {
+			listing.WriteString(name + "\n")
+		}
+		content = strings.NewReader(listing.String())
+		size = int64(listing.Len())
+	}
+
+	ranges, terr := t.localRanges(size)
+	if terr != nil {
+		return terr
+	}
+	name, terr := t.outputName(u.Path, "")
+	if terr != nil {
+		return terr
+	}
+	out, terr := t.openOutput(name, false)
+	if terr != nil {
+		return terr
+	}
+	defer out.Close()
+
+	t.Info["size_download"] = int64(0)
+	if t.Config.NoBody {
+		return nil
+	}
+	var total int64
+	for _, r := range ranges {
+		total += max(r.Len(), 0)
+	}
+	t.dlTotal.Store(total)
+	for _, r := range ranges {
+		part := io.NewSectionReader(content, r.Start, r.Len())
+		if terr := t.copyBody(out, t.rangeWriter(out, r.Start), part); terr != nil {
+			return terr
+		}
+	}
+
+	if t.Config.RemoteTime && out.file != nil {
+		SetFileTime(info.ModTime().Unix(), out.name)
+	}
+	return nil
+}
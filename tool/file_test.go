@@ -0,0 +1,96 @@
+package tool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPerformFile(t *testing.T) {
+	dir := isolateCurlRC(t)
+	src := filepath.Join(dir, "src.txt")
+	if err := os.WriteFile(src, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fileURL := "file://" + src
+
+	testCases := []struct {
+		args []string
+		want string
+	}{
+		{nil, "hello world"},
+		{[]string{"-r", "0-4"}, "hello"},
+		{[]string{"-r", "-5"}, "world"},
+		{[]string{"-r", "6-"}, "world"},
+		{[]string{"-r", "0-1,6-7"}, "hewo"},
+		{[]string{"-C", "6"}, "world"},
+	}
+	for _, tc := range testCases {
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, append(tc.args, fileURL)); err != nil {
+			t.Errorf("Operate(%q) failed: %v", tc.args, err)
+			continue
+		}
+		if stdout.String() != tc.want {
+			t.Errorf("Operate(%q) output = %q; want %q", tc.args, stdout.String(), tc.want)
+		}
+	}
+
+	t.Run("directory", func(t *testing.T) {
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{"file://" + dir}); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if stdout.String() != "src.txt\n" {
+			t.Errorf("listing = %q; want %q", stdout.String(), "src.txt\n")
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		for _, existing := range []string{"hello", "hello world"} {
+			if err := os.WriteFile(out, []byte(existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+			global, _, _ := newTestGlobal()
+			if err := Operate(context.Background(), global, []string{"-C", "-", "-o", out, fileURL}); err != nil {
+				t.Fatalf("Operate() failed: %v", err)
+			}
+			if got, _ := os.ReadFile(out); string(got) != "hello world" {
+				t.Errorf("from %q: file = %q; want %q", existing, got, "hello world")
+			}
+		}
+	})
+
+	t.Run("sparse ranges", func(t *testing.T) {
+		out := filepath.Join(t.TempDir(), "out.txt")
+		if err := os.WriteFile(out, []byte("..........."), 0644); err != nil {
+			t.Fatal(err)
+		}
+		global, _, _ := newTestGlobal()
+		args := []string{"--sparse-ranges", "-r", "0-1,6-7", "-o", out, fileURL}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if got, _ := os.ReadFile(out); string(got) != "he....wo..." {
+			t.Errorf("file = %q; want %q", got, "he....wo...")
+		}
+	})
+
+	errorCases := []struct {
+		args []string
+		want CurlCode
+	}{
+		{[]string{"file://" + filepath.Join(dir, "missing")}, CurlFileCouldntReadFile},
+		{[]string{"file://example.com" + src}, CurlURLMalformat},
+		{[]string{"-r", "20-", fileURL}, CurlBadDownloadResume},
+		{[]string{"-r", "9-5", fileURL}, CurlRangeError},
+	}
+	for _, tc := range errorCases {
+		global, _, _ := newTestGlobal()
+		if code := ErrorCode(Operate(context.Background(), global, tc.args)); code != tc.want {
+			t.Errorf("Operate(%q) code = %d; want %d", tc.args, code, tc.want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package tool
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// This file turns the parts collected from -F/--form and --form-string
+// into a multipart/form-data request body. It plays the role of the mime
+// code libcurl runs for the `curl_mime` tree built by `tool2curlmime` in
+// curl-src/src/tool_formparse.c. File contents are not loaded into memory:
+// the body is a list of segments, and files are opened and streamed only
+// when the request is sent.
+
+// formSegment is a piece of a multipart body: either bytes held in memory
+// or a file that is read, through encoder if set, when the body is sent.
+type formSegment struct {
+	data    []byte
+	path    string
+	encoder string
+}
+
+// formBody is a multipart body ready to be sent.
+type formBody struct {
+	ContentType string // multipart/form-data with its boundary
+	Size        int64  // the length of the body in bytes, -1 if unknown
+
+	segments []formSegment
+}
+
+// formBuilder accumulates the segments of a multipart body.
+type formBuilder struct {
+	buf        bytes.Buffer
+	segments   []formSegment
+	size       int64
+	formEscape bool // --form-escape
+}
+
+func (b *formBuilder) writeString(s string) {
+	b.buf.WriteString(s)
+}
+
+// flush turns the buffered bytes into a segment.
+func (b *formBuilder) flush() {
+	if b.buf.Len() > 0 {
+		data := append([]byte(nil), b.buf.Bytes()...)
+		b.segments = append(b.segments, formSegment{data: data})
+		b.addSize(int64(len(data)))
+		b.buf.Reset()
+	}
+}
+
+// addSize adds n bytes to the size of the body. A size of -1 is unknown
+// and makes the whole size unknown.
+func (b *formBuilder) addSize(n int64) {
+	if n < 0 || b.size < 0 {
+		b.size = -1
+		return
+	}
+	b.size += n
+}
+
+// addData adds data, encoded with encoder if it is set.
+func (b *formBuilder) addData(data []byte, encoder string) error {
+	if encoder != "" {
+		var err error
+		if data, err = encodeFormData(encoder, data); err != nil {
+			return err
+		}
+	}
+	b.buf.Write(data)
+	return nil
+}
+
+// addFile adds the content of the file at path. The file is only checked
+// here; it is read when the body is sent. Stdin, "-", cannot be read twice
+// and is read into memory right away, as in curl.
+func (b *formBuilder) addFile(path, encoder string) error {
+	if path == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return newTransferError(CurlReadError, "")
+		}
+		return b.addData(data, encoder)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return newTransferError(CurlReadError, "")
+	}
+	b.flush()
+	b.segments = append(b.segments, formSegment{path: path, encoder: encoder})
+	b.addSize(encodedSize(encoder, info.Size()))
+	return nil
+}
+
+// newFormBoundary returns a random boundary in the format curl uses: 24
+// dashes followed by 22 random alphanumeric characters.
+func newFormBoundary() string {
+	const chars = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	random := make([]byte, 22)
+	rand.Read(random)
+	for i, c := range random {
+		random[i] = chars[int(c)%len(chars)]
+	}
+	return strings.Repeat("-", 24) + string(random)
+}
+
+// formContentTypes maps file name extensions to the content types curl
+// assumes for uploaded files, from `ContentTypeForFilename` in libcurl.
+var formContentTypes = map[string]string{
+	".gif":  "image/gif",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".svg":  "image/svg+xml",
+	".txt":  "text/plain",
+	".htm":  "text/html",
+	".html": "text/html",
+	".pdf":  "application/pdf",
+	".xml":  "application/xml",
+}
+
+// formFileContentType returns the content type for an uploaded file.
+func formFileContentType(name string) string {
+	if ct, ok := formContentTypes[strings.ToLower(filepath.Ext(name))]; ok {
+		return ct
+	}
+	return "application/octet-stream"
+}
+
+// escapeFormField escapes a name or file name for a Content-Disposition
+// header. By default this is done the way browsers do; with --form-escape
+// backslashes and double quotes are escaped with a backslash instead.
+func (b *formBuilder) escapeFormField(s string) string {
+	if b.formEscape {
+		return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+	}
+	return strings.NewReplacer(`"`, "%22", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// hasHeader reports whether headers has a header called name.
+func hasHeader(headers []string, name string) bool {
+	for _, h := range headers {
+		if key, _, ok := strings.Cut(h, ":"); ok && strings.EqualFold(strings.TrimSpace(key), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// partFilename returns the file name to send for a part, if any. Files
+// uploaded with @ are sent with the base name of their path unless
+// filename= gives another name; other parts only have a file name when
+// filename= sets one.
+func partFilename(part *FormPart) string {
+	if part.Filename != part.Value {
+		return part.Filename
+	}
+	if part.Type == PartTypeFile && part.Value != "-" {
+		return filepath.Base(part.Value)
+	}
+	return ""
+}
+
+// writeParts writes parts as a multipart body delimited by boundary. Like
+// libcurl, the parts of a form get a "form-data" disposition, and those of
+// other multiparts an "attachment" disposition when they have a name or a
+// file name. Headers the user gives for a part replace the ones that would
+// be generated.
+func (b *formBuilder) writeParts(parts []*FormPart, boundary string, formData bool) error {
+	for _, part := range parts {
+		b.writeString("--" + boundary + "\r\n")
+
+		filename := partFilename(part)
+		disposition := ""
+		switch {
+		case formData:
+			disposition = "form-data"
+		case part.Name != "" || filename != "":
+			disposition = "attachment"
+		}
+		if disposition != "" && !hasHeader(part.Headers, "Content-Disposition") {
+			if part.Name != "" {
+				disposition += `; name="` + b.escapeFormField(part.Name) + `"`
+			}
+			if filename != "" {
+				disposition += `; filename="` + b.escapeFormField(filename) + `"`
+			}
+			b.writeString("Content-Disposition: " + disposition + "\r\n")
+		}
+
+		multipart := part.Type == PartTypeMultipart
+		contentType := part.ContentType
+		var subBoundary string
+		switch {
+		case multipart:
+			subBoundary = newFormBoundary()
+			if contentType == "" {
+				contentType = "multipart/mixed"
+			}
+			contentType += "; boundary=" + subBoundary
+		case contentType == "" && part.Type == PartTypeFile:
+			contentType = formFileContentType(part.Value)
+		}
+		if contentType != "" && !hasHeader(part.Headers, "Content-Type") {
+			b.writeString("Content-Type: " + contentType + "\r\n")
+		}
+
+		encoder := part.Encoder
+		if multipart {
+			encoder = ""
+		} else if encoder != "" {
+			if _, ok := formEncoders[encoder]; !ok {
+				return newTransferError(CurlBadFunctionArgument, "")
+			}
+			if !hasHeader(part.Headers, "Content-Transfer-Encoding") {
+				b.writeString("Content-Transfer-Encoding: " + encoder + "\r\n")
+			}
+		}
+		for _, h := range part.Headers {
+			b.writeString(h + "\r\n")
+		}
+		b.writeString("\r\n")
+
+		var err error
+		switch {
+		case multipart:
+			err = b.writeParts(part.Parts, subBoundary, false)
+		case part.Type == PartTypeLiteral:
+			err = b.addData([]byte(part.Value), encoder)
+		default:
+			err = b.addFile(part.Value, encoder)
+		}
+		if err != nil {
+			return err
+		}
+		b.writeString("\r\n")
+	}
+	b.writeString("--" + boundary + "--")
+	return nil
+}
+
+// newFormBody lays out a multipart/form-data body for parts. Files named
+// by the parts must exist but are not read yet. Errors that do not carry a
+// result code of their own are read errors.
+func newFormBody(parts []*FormPart, formEscape bool) (*formBody, *TransferError) {
+	boundary := newFormBoundary()
+	b := &formBuilder{formEscape: formEscape}
+	if err := b.writeParts(parts, boundary, true); err != nil {
+		var terr *TransferError
+		if errors.As(err, &terr) {
+			return nil, terr
+		}
+		return nil, newTransferError(CurlReadError, "%v", err)
+	}
+	b.writeString("\r\n")
+	b.flush()
+	return &formBody{
+		ContentType: "multipart/form-data; boundary=" + boundary,
+		Size:        b.size,
+		segments:    b.segments,
+	}, nil
+}
+
+// Reader returns a reader that produces the body, opening each file as it
+// is reached. Closing it closes the file being read.
+func (f *formBody) Reader() io.ReadCloser {
+	return &formReader{segments: f.segments}
+}
+
+// formReader streams the segments of a formBody.
+type formReader struct {
+	segments []formSegment
+	current  io.Reader
+	file     *os.File
+}
+
+func (r *formReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.segments) == 0 {
+				return 0, io.EOF
+			}
+			seg := r.segments[0]
+			r.segments = r.segments[1:]
+			if seg.path == "" {
+				r.current = bytes.NewReader(seg.data)
+			} else {
+				file, err := os.Open(seg.path)
+				if err != nil {
+					return 0, newTransferError(CurlReadError, "")
+				}
+				r.file = file
+				r.current = file
+				if seg.encoder != "" {
+					r.current = newEncodeReader(file, seg.encoder)
+				}
+			}
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *formReader) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
@@ -0,0 +1,262 @@
+package tool
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPart is a part read back from a multipart body.
+type testPart struct {
+	Header  textproto.MIMEHeader
+	Content string
+}
+
+// readForm sends body through a multipart reader and returns its parts.
+func readForm(t *testing.T, body *formBody) []testPart {
+	t.Helper()
+	data, err := io.ReadAll(body.Reader())
+	if err != nil {
+		t.Fatalf("reading the body failed: %v", err)
+	}
+	if body.Size >= 0 && int64(len(data)) != body.Size {
+		t.Errorf("Size = %d; the body has %d bytes", body.Size, len(data))
+	}
+	mediaType, params, err := mime.ParseMediaType(body.ContentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("ContentType = %q", body.ContentType)
+	}
+	return readParts(t, string(data), params["boundary"])
+}
+
+func readParts(t *testing.T, data, boundary string) []testPart {
+	t.Helper()
+	var parts []testPart
+	mr := multipart.NewReader(strings.NewReader(data), boundary)
+	for {
+		part, err := mr.NextRawPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("NextRawPart() failed: %v", err)
+		}
+		content, _ := io.ReadAll(part)
+		parts = append(parts, testPart{part.Header, string(content)})
+	}
+}
+
+func TestNewFormBody(t *testing.T) {
+	dir := t.TempDir()
+	textFile := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(textFile, []byte("file content\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	binFile := filepath.Join(dir, "blob")
+	if err := os.WriteFile(binFile, []byte{0, 1, 2}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	formParts := []*FormPart{
+		{Name: "text", Value: "hello", Type: PartTypeLiteral, Filename: "hello"},
+		{Name: "typed", Value: "<b>", Type: PartTypeLiteral, Filename: "<b>", ContentType: "text/html"},
+		{Name: "upload", Value: textFile, Type: PartTypeFile, Filename: textFile, Headers: []string{"X-Extra: yes"}},
+		{Name: "renamed", Value: binFile, Type: PartTypeFile, Filename: `my "blob"`},
+		{Name: "inline", Value: textFile, Type: PartTypeDataFile, Filename: textFile},
+	}
+	body, err := newFormBody(formParts, false)
+	if err != nil {
+		t.Fatalf("newFormBody() failed: %v", err)
+	}
+
+	testCases := []struct {
+		disposition string
+		contentType string
+		extra       string
+		content     string
+	}{
+		{`form-data; name="text"`, "", "", "hello"},
+		{`form-data; name="typed"`, "text/html", "", "<b>"},
+		{`form-data; name="upload"; filename="notes.txt"`, "text/plain", "yes", "file content\n"},
+		{`form-data; name="renamed"; filename="my %22blob%22"`, "application/octet-stream", "", "\x00\x01\x02"},
+		{`form-data; name="inline"`, "", "", "file content\n"},
+	}
+	parts := readForm(t, body)
+	if len(parts) != len(testCases) {
+		t.Fatalf("got %d parts; want %d", len(parts), len(testCases))
+	}
+	for i, tc := range testCases {
+		part := parts[i]
+		if got := part.Header.Get("Content-Disposition"); got != tc.disposition {
+			t.Errorf("part %d Content-Disposition = %q; want %q", i, got, tc.disposition)
+		}
+		if got := part.Header.Get("Content-Type"); got != tc.contentType {
+			t.Errorf("part %d Content-Type = %q; want %q", i, got, tc.contentType)
+		}
+		if got := part.Header.Get("X-Extra"); got != tc.extra {
+			t.Errorf("part %d X-Extra = %q; want %q", i, got, tc.extra)
+		}
+		if part.Content != tc.content {
+			t.Errorf("part %d content = %q; want %q", i, part.Content, tc.content)
+		}
+	}
+}
+
+func TestNewFormBody_MultipleFiles(t *testing.T) {
+	dir := t.TempDir()
+	var files []string
+	for _, name := range []string{"a.txt", "b.png"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, path)
+	}
+
+	parser := NewParameterParser(NewGlobalConfig())
+	if err := parser.Parse([]string{"-F", "files=@" + files[0] + "," + files[1]}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	body, err := newFormBody(parser.Global.Last.Form, false)
+	if err != nil {
+		t.Fatalf("newFormBody() failed: %v", err)
+	}
+
+	parts := readForm(t, body)
+	if len(parts) != 1 || parts[0].Header.Get("Content-Disposition") != `form-data; name="files"` {
+		t.Fatalf("parts = %+v; want one part named files", parts)
+	}
+	mediaType, params, _ := mime.ParseMediaType(parts[0].Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Content-Type = %q; want multipart/mixed", mediaType)
+	}
+	subparts := readParts(t, parts[0].Content, params["boundary"])
+	if len(subparts) != 2 {
+		t.Fatalf("got %d files; want 2", len(subparts))
+	}
+	for i, want := range []string{`attachment; filename="a.txt"`, `attachment; filename="b.png"`} {
+		if got := subparts[i].Header.Get("Content-Disposition"); got != want {
+			t.Errorf("file %d Content-Disposition = %q; want %q", i, got, want)
+		}
+	}
+	if got := subparts[1].Header.Get("Content-Type"); got != "image/png" {
+		t.Errorf("file 1 Content-Type = %q; want image/png", got)
+	}
+}
+
+func TestNewFormBody_MissingFile(t *testing.T) {
+	parts := []*FormPart{{Name: "f", Value: filepath.Join(t.TempDir(), "nope"), Type: PartTypeFile}}
+	if _, err := newFormBody(parts, false); err == nil {
+		t.Error("newFormBody() should fail for a missing file")
+	}
+}
+
+func TestNewFormBody_Nested(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "body.txt")
+	if err := os.WriteFile(file, []byte("caf\xc3\xa9 = coffee\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parser := NewParameterParser(NewGlobalConfig())
+	args := []string{
+		"-F", "mail=(;type=multipart/alternative",
+		"-F", "=<" + file + ";type=text/plain;encoder=quoted-printable",
+		"-F", "=hello <b>world</b>;type=text/html;encoder=base64",
+		"-F", "=)",
+		"-F", `sig=@` + file + `;filename=a"b\c.txt;encoder=8bit`,
+		"--form-escape",
+	}
+	if err := parser.Parse(args); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	config := parser.Global.Last
+	body, err := newFormBody(config.Form, config.FormEscape)
+	if err != nil {
+		t.Fatalf("newFormBody() failed: %v", err)
+	}
+	if body.Size != -1 {
+		t.Errorf("Size = %d; want -1 for a quoted-printable file", body.Size)
+	}
+
+	parts := readForm(t, body)
+	if len(parts) != 2 {
+		t.Fatalf("got %d parts; want 2", len(parts))
+	}
+	mediaType, params, _ := mime.ParseMediaType(parts[0].Header.Get("Content-Type"))
+	if mediaType != "multipart/alternative" || parts[0].Header.Get("Content-Disposition") != `form-data; name="mail"` {
+		t.Fatalf("mail part header = %v", parts[0].Header)
+	}
+	if got, want := parts[1].Header.Get("Content-Disposition"), `form-data; name="sig"; filename="a\"b\\c.txt"`; got != want {
+		t.Errorf("Content-Disposition = %q; want %q", got, want)
+	}
+	if got := parts[1].Header.Get("Content-Transfer-Encoding"); got != "8bit" {
+		t.Errorf("Content-Transfer-Encoding = %q; want 8bit", got)
+	}
+
+	alternatives := readParts(t, parts[0].Content, params["boundary"])
+	testCases := []struct {
+		contentType string
+		encoding    string
+		content     string
+	}{
+		{"text/plain", "quoted-printable", "caf=C3=A9 =3D coffee\r\n"},
+		{"text/html", "base64", "aGVsbG8gPGI+d29ybGQ8L2I+"},
+	}
+	if len(alternatives) != len(testCases) {
+		t.Fatalf("got %d alternatives; want %d", len(alternatives), len(testCases))
+	}
+	for i, tc := range testCases {
+		part := alternatives[i]
+		if got := part.Header.Get("Content-Disposition"); got != "" {
+			t.Errorf("alternative %d Content-Disposition = %q; want none", i, got)
+		}
+		if got := part.Header.Get("Content-Type"); got != tc.contentType {
+			t.Errorf("alternative %d Content-Type = %q; want %q", i, got, tc.contentType)
+		}
+		if got := part.Header.Get("Content-Transfer-Encoding"); got != tc.encoding {
+			t.Errorf("alternative %d Content-Transfer-Encoding = %q; want %q", i, got, tc.encoding)
+		}
+		if part.Content != tc.content {
+			t.Errorf("alternative %d content = %q; want %q", i, part.Content, tc.content)
+		}
+	}
+}
+
+func TestNewFormBody_EncoderErrors(t *testing.T) {
+	testCases := []struct {
+		part *FormPart
+		want CurlCode
+	}{
+		{&FormPart{Name: "a", Value: "x", Type: PartTypeLiteral, Encoder: "rot13"}, CurlBadFunctionArgument},
+		{&FormPart{Name: "a", Value: "caf\xc3\xa9", Type: PartTypeLiteral, Encoder: "7bit"}, CurlBadContentEncoding},
+	}
+	for _, tc := range testCases {
+		_, err := newFormBody([]*FormPart{tc.part}, false)
+		if code := ErrorCode(err); code != tc.want {
+			t.Errorf("newFormBody(%+v) code = %d; want %d", tc.part, code, tc.want)
+		}
+	}
+}
+
+// failingEncoder is an encoder whose writes fail with a plain error.
+type failingEncoder struct{}
+
+func (failingEncoder) Write([]byte) (int, error) { return 0, errors.New("encoder failed") }
+func (failingEncoder) Close() error              { return nil }
+
+func TestNewFormBody_PlainErrors(t *testing.T) {
+	formEncoders["failing"] = func(io.Writer) io.WriteCloser { return failingEncoder{} }
+	defer delete(formEncoders, "failing")
+
+	part := &FormPart{Name: "a", Value: "x", Type: PartTypeLiteral, Encoder: "failing"}
+	_, err := newFormBody([]*FormPart{part}, false)
+	if err == nil || err.Code != CurlReadError {
+		t.Errorf("newFormBody() = %v; want a read error", err)
+	}
+}
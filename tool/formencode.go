@@ -0,0 +1,275 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+)
+
+// This file contains the transfer encodings that can be applied to the
+// parts of a form with "encoder=". They follow the encoders of libcurl's
+// lib/mime.c byte for byte, so that the bodies sent are the ones curl
+// sends.
+
+// maxEncodedLineLength is the C `MAX_ENCODED_LINE_LENGTH`, the longest line
+// produced by the base64 and quoted-printable encoders.
+const maxEncodedLineLength = 76
+
+// formEncoders are the encoders known by name. Each returns a writer that
+// encodes what is written to it into w; closing it flushes the end of the
+// data without closing w.
+var formEncoders = map[string]func(w io.Writer) io.WriteCloser{
+	"binary":           newNopEncoder,
+	"8bit":             newNopEncoder,
+	"7bit":             func(w io.Writer) io.WriteCloser { return &sevenBitEncoder{w: w} },
+	"base64":           func(w io.Writer) io.WriteCloser { return base64.NewEncoder(base64.StdEncoding, &lineBreaker{w: w}) },
+	"quoted-printable": func(w io.Writer) io.WriteCloser { return &qpEncoder{w: w} },
+}
+
+// encodedSize returns the size of size bytes of data once encoded, or -1
+// when it cannot be known without reading the data.
+func encodedSize(encoder string, size int64) int64 {
+	switch encoder {
+	case "base64":
+		if size == 0 {
+			return 0
+		}
+		size = 4 * (1 + (size-1)/3)
+		// Count the CRLFs between lines.
+		return size + 2*((size-1)/maxEncodedLineLength)
+	case "quoted-printable":
+		return -1
+	default:
+		return size
+	}
+}
+
+// encodeFormData returns data encoded with encoder.
+func encodeFormData(encoder string, data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	enc := formEncoders[encoder](&out)
+	if _, err := enc.Write(data); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+type nopEncoder struct {
+	io.Writer
+}
+
+func newNopEncoder(w io.Writer) io.WriteCloser {
+	return nopEncoder{w}
+}
+
+func (nopEncoder) Close() error {
+	return nil
+}
+
+// sevenBitEncoder passes data through, failing on any byte that does not
+// fit in 7 bits.
+type sevenBitEncoder struct {
+	w io.Writer
+}
+
+func (e *sevenBitEncoder) Write(p []byte) (int, error) {
+	for _, c := range p {
+		if c&0x80 != 0 {
+			return 0, newTransferError(CurlBadContentEncoding, "")
+		}
+	}
+	return e.w.Write(p)
+}
+
+func (e *sevenBitEncoder) Close() error {
+	return nil
+}
+
+// lineBreaker inserts a CRLF every maxEncodedLineLength bytes, but not at
+// the end of the data.
+type lineBreaker struct {
+	w   io.Writer
+	pos int
+}
+
+func (l *lineBreaker) Write(p []byte) (int, error) {
+	out := make([]byte, 0, len(p)+len(p)/maxEncodedLineLength*2+2)
+	for _, c := range p {
+		if l.pos == maxEncodedLineLength {
+			out = append(out, '\r', '\n')
+			l.pos = 0
+		}
+		out = append(out, c)
+		l.pos++
+	}
+	if _, err := l.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Character classes of the quoted-printable encoder.
+const (
+	qpEscape = iota // must be encoded as =XX
+	qpOK            // kept as it is
+	qpSpace         // space or tab: encoded before a line end only
+	qpCR            // kept as it is when followed by a line feed
+)
+
+func qpClass(c byte) int {
+	switch {
+	case c == ' ' || c == '\t':
+		return qpSpace
+	case c == '\r':
+		return qpCR
+	case c > ' ' && c < 0x7f && c != '=':
+		return qpOK
+	default:
+		return qpEscape
+	}
+}
+
+// qpEncoder is the quoted-printable encoder, the C `encoder_qp_read`. CRLF
+// pairs are kept as line breaks, other control characters are encoded, and
+// lines longer than maxEncodedLineLength are split with soft line breaks.
+type qpEncoder struct {
+	w       io.Writer
+	pending []byte // input waiting for lookahead
+	pos     int    // the length of the current output line
+}
+
+func (e *qpEncoder) Write(p []byte) (int, error) {
+	e.pending = append(e.pending, p...)
+	if err := e.encode(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (e *qpEncoder) Close() error {
+	return e.encode(true)
+}
+
+// lookaheadEOL tells whether the pending input at offset n is a CRLF or
+// the end of the data: 1 if it is, 0 if not, and -1 when more input is
+// needed to tell.
+func (e *qpEncoder) lookaheadEOL(n int, atEOF bool) int {
+	rest := e.pending[n:]
+	switch {
+	case len(rest) >= 2:
+		if rest[0] == '\r' && rest[1] == '\n' {
+			return 1
+		}
+		return 0
+	case !atEOF:
+		return -1
+	case len(rest) == 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// encode encodes as much of the pending input as the lookahead allows, or
+// all of it at the end of the data.
+func (e *qpEncoder) encode(atEOF bool) error {
+	const hex = "0123456789ABCDEF"
+	var out bytes.Buffer
+	i := 0
+loop:
+	for i < len(e.pending) {
+		c := e.pending[i]
+		buf := []byte{'=', hex[c>>4], hex[c&0xf]}
+		consumed := 1
+
+		switch qpClass(c) {
+		case qpOK:
+			buf = []byte{c}
+		case qpSpace:
+			// Spacing must be encoded when followed by a line end.
+			switch e.lookaheadEOL(i+1, atEOF) {
+			case -1:
+				break loop
+			case 0:
+				buf = []byte{c}
+			}
+		case qpCR:
+			switch e.lookaheadEOL(i, atEOF) {
+			case -1:
+				break loop
+			case 1:
+				buf = []byte("\r\n")
+				consumed = 2
+			}
+		}
+
+		// Make sure the encoded character fits on the line.
+		if buf[len(buf)-1] != '\n' {
+			soft := e.pos+len(buf) > maxEncodedLineLength
+			if !soft && e.pos+len(buf) == maxEncodedLineLength {
+				// The line may only be filled up before a line end.
+				switch e.lookaheadEOL(i+consumed, atEOF) {
+				case -1:
+					break loop
+				case 0:
+					soft = true
+				}
+			}
+			if soft {
+				buf = []byte("=\r\n")
+				consumed = 0
+			}
+		}
+
+		out.Write(buf)
+		e.pos += len(buf)
+		if buf[len(buf)-1] == '\n' {
+			e.pos = 0
+		}
+		i += consumed
+	}
+	e.pending = append(e.pending[:0], e.pending[i:]...)
+	_, err := e.w.Write(out.Bytes())
+	return err
+}
+
+// encodeReader reads src through an encoder.
+type encodeReader struct {
+	src  io.Reader
+	enc  io.WriteCloser
+	out  bytes.Buffer
+	buf  []byte
+	done bool
+}
+
+func newEncodeReader(src io.Reader, encoder string) *encodeReader {
+	r := &encodeReader{src: src, buf: make([]byte, 32*1024)}
+	r.enc = formEncoders[encoder](&r.out)
+	return r
+}
+
+func (r *encodeReader) Read(p []byte) (int, error) {
+	for r.out.Len() == 0 && !r.done {
+		n, err := r.src.Read(r.buf)
+		if n > 0 {
+			if _, werr := r.enc.Write(r.buf[:n]); werr != nil {
+				return 0, werr
+			}
+		}
+		if err == io.EOF {
+			r.done = true
+			if cerr := r.enc.Close(); cerr != nil {
+				return 0, cerr
+			}
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	if r.out.Len() == 0 {
+		return 0, io.EOF
+	}
+	return r.out.Read(p)
+}
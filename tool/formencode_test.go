@@ -0,0 +1,93 @@
+package tool
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestEncodeFormData(t *testing.T) {
+	testCases := []struct {
+		encoder string
+		input   string
+		want    string
+	}{
+		{"8bit", "caf\xc3\xa9", "caf\xc3\xa9"},
+		{"binary", "\x00\xff", "\x00\xff"},
+		{"7bit", "plain text\r\n", "plain text\r\n"},
+		{"base64", "", ""},
+		{"base64", "hello", "aGVsbG8="},
+		{"base64", strings.Repeat("x", 60), strings.Repeat("eHh4", 19) + "\r\n" + "eHh4"},
+		{"quoted-printable", "hello world", "hello world"},
+		{"quoted-printable", "a=b", "a=3Db"},
+		{"quoted-printable", "trailing \r\nline", "trailing=20\r\nline"},
+		{"quoted-printable", "tab\t", "tab=09"},
+		{"quoted-printable", "lone\nfeed\r", "lone=0Afeed=0D"},
+		{"quoted-printable", "caf\xc3\xa9", "caf=C3=A9"},
+		{"quoted-printable", strings.Repeat("a", 76), strings.Repeat("a", 76)},
+		{"quoted-printable", strings.Repeat("a", 80), strings.Repeat("a", 75) + "=\r\n" + "aaaaa"},
+		{"quoted-printable", strings.Repeat("a", 74) + "\xff", strings.Repeat("a", 74) + "=\r\n=FF"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.encoder+" "+tc.input, func(t *testing.T) {
+			got, err := encodeFormData(tc.encoder, []byte(tc.input))
+			if err != nil {
+				t.Fatalf("encodeFormData() failed: %v", err)
+			}
+			if string(got) != tc.want {
+				t.Errorf("encodeFormData() = %q; want %q", got, tc.want)
+			}
+			if size := encodedSize(tc.encoder, int64(len(tc.input))); size >= 0 && size != int64(len(got)) {
+				t.Errorf("encodedSize() = %d; want %d", size, len(got))
+			}
+		})
+	}
+
+	_, err := encodeFormData("7bit", []byte("caf\xc3\xa9"))
+	if code := ErrorCode(err); code != CurlBadContentEncoding {
+		t.Errorf("7bit encoding of 8-bit data: code = %d; want %d", code, CurlBadContentEncoding)
+	}
+}
+
+func TestEncodeReader(t *testing.T) {
+	var input bytes.Buffer
+	for i := 0; i < 2000; i++ {
+		input.WriteString("line with = and trailing space \r\n\xe9")
+	}
+	for _, encoder := range []string{"base64", "quoted-printable"} {
+		want, err := encodeFormData(encoder, input.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Feed the reader a byte at a time to exercise the lookahead.
+		got, err := io.ReadAll(newEncodeReader(&oneByteReader{input.Bytes()}, encoder))
+		if err != nil {
+			t.Fatalf("%s: reading failed: %v", encoder, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: streamed encoding differs from encodeFormData()", encoder)
+		}
+	}
+
+	_, err := io.ReadAll(newEncodeReader(strings.NewReader("caf\xc3\xa9"), "7bit"))
+	var te *TransferError
+	if !errors.As(err, &te) || te.Code != CurlBadContentEncoding {
+		t.Errorf("7bit read error = %v; want code %d", err, CurlBadContentEncoding)
+	}
+}
+
+// oneByteReader returns its data one byte per Read.
+type oneByteReader struct {
+	data []byte
+}
+
+func (r *oneByteReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		return 0, io.EOF
+	}
+	p[0] = r.data[0]
+	r.data = r.data[1:]
+	return 1, nil
+}
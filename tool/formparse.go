@@ -1,7 +1,9 @@
 package tool
 
 import (
+	"bufio"
 	"fmt"
+	"os"
 	"strings"
 )
 
@@ -9,9 +11,10 @@ import (
 type FormPartType int
 
 const (
-	PartTypeLiteral  FormPartType = iota // e.g., name=value
-	PartTypeFile                       // e.g., name=@file.txt
-	PartTypeDataFile                   // e.g., name=<file.txt
+	PartTypeLiteral   FormPartType = iota // e.g., name=value
+	PartTypeFile                          // e.g., name=@file.txt
+	PartTypeDataFile                      // e.g., name=<file.txt
+	PartTypeMultipart                     // e.g., name=(;type=multipart/mixed
 )
 
 // FormPart represents a single part of a multipart form submission.
@@ -24,6 +27,7 @@ type FormPart struct {
 	Filename    string // Can be used to override the original filename
 	Encoder     string
 	Headers     []string
+	Parts       []*FormPart // the parts of a PartTypeMultipart part
 }
 
 // parser holds the state for parsing a form string. It allows us to process
@@ -78,17 +82,15 @@ func (p *parser) getWord(endChars string) string {
 // ParseFormString parses a curl-style form string (e.g., "name=value;type=...").
 // It returns a slice of FormPart structs, as one string can define multiple parts.
 // This is a translation of the C function `formparse`.
-// Note: The C version also handles multipart boundaries with '(' and ')', which
-// is omitted here for simplicity as it relates to higher-level state management.
+// A value starting with '(' returns a single PartTypeMultipart part, which
+// the following -F options fill until "=)" closes it; that state is kept by
+// the caller. The name may be empty, as for the parts of such a multipart.
 func ParseFormString(input string) ([]*FormPart, error) {
 	name, content, found := strings.Cut(input, "=")
 	if !found {
 		return nil, fmt.Errorf("invalid form string: missing '='")
 	}
 	name = strings.TrimSpace(name)
-	if name == "" {
-		return nil, fmt.Errorf("form part has no name")
-	}
 
 	var parts []*FormPart
 	p := &parser{input: content}
@@ -103,19 +105,30 @@ func ParseFormString(input string) ([]*FormPart, error) {
 			return nil, fmt.Errorf("form part has no value")
 		}
 
-		// Determine value type
-		if p.input[p.pos] == '@' {
+		// Determine value type. The files after a comma are files too,
+		// with or without their own '@': "@a,b" or "@a,@b".
+		if len(parts) > 0 {
+			part.Type = parts[0].Type
+			if p.input[p.pos] == '@' {
+				p.pos++
+			}
+		} else if p.input[p.pos] == '@' {
 			part.Type = PartTypeFile
 			p.pos++
 		} else if p.input[p.pos] == '<' {
 			part.Type = PartTypeDataFile
 			p.pos++
+		} else if p.input[p.pos] == '(' {
+			part.Type = PartTypeMultipart
+			p.pos++
 		} else {
 			part.Type = PartTypeLiteral
 		}
 
-		part.Value = p.getWord(";,")
-		part.Filename = part.Value // Default filename is the value itself
+		if part.Type != PartTypeMultipart {
+			part.Value = p.getWord(";,")
+			part.Filename = part.Value // Default filename is the value itself
+		}
 
 		// Parse semicolon-separated attributes
 		for p.pos < len(p.input) && p.input[p.pos] == ';' {
@@ -132,8 +145,15 @@ func ParseFormString(input string) ([]*FormPart, error) {
 				case "encoder":
 					part.Encoder = val
 				case "headers":
-					// Simplified: doesn't support reading headers from a file (@)
-					part.Headers = append(part.Headers, val)
+					if file, ok := strings.CutPrefix(val, "@"); ok {
+						headers, err := readFormHeaders(file)
+						if err != nil {
+							return nil, err
+						}
+						part.Headers = append(part.Headers, headers...)
+					} else {
+						part.Headers = append(part.Headers, val)
+					}
 				}
 			}
 		}
@@ -144,10 +164,40 @@ func ParseFormString(input string) ([]*FormPart, error) {
 		}
 		p.pos++ // Skip comma
 
-		if part.Type == PartTypeLiteral {
-			return nil, fmt.Errorf("literal form parts cannot be comma-separated")
+		if part.Type == PartTypeLiteral || part.Type == PartTypeMultipart {
+			return nil, fmt.Errorf("only files can be comma-separated")
 		}
 	}
 
 	return parts, nil
-}
\ No newline at end of file
+}
+
+// readFormHeaders reads the headers of a part from a file, as given with
+// "headers=@file". This is the C function `read_field_headers`: each line
+// is a header, lines starting with '#' are comments and a line starting
+// with white space continues the previous header.
+func readFormHeaders(filename string) ([]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read from %s: %w", filename, err)
+	}
+	defer file.Close()
+
+	var headers []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		switch {
+		case strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#"):
+			continue
+		case (line[0] == ' ' || line[0] == '\t') && len(headers) > 0:
+			headers[len(headers)-1] += " " + strings.TrimSpace(line)
+		default:
+			headers = append(headers, strings.TrimSpace(line))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read from %s: %w", filename, err)
+	}
+	return headers, nil
+}
@@ -1,6 +1,8 @@
 package tool
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -63,15 +65,39 @@ func TestParseFormString(t *testing.T) {
 				{Name: "assets", Value: "b.zip", Type: PartTypeFile, Filename: "b archive.zip"},
 			},
 		},
+		{
+			name:  "files after a comma",
+			input: "images=@img1.jpg,img2.png",
+			expected: []*FormPart{
+				{Name: "images", Value: "img1.jpg", Type: PartTypeFile, Filename: "img1.jpg"},
+				{Name: "images", Value: "img2.png", Type: PartTypeFile, Filename: "img2.png"},
+			},
+		},
 		{
 			name:    "missing equals",
 			input:   "namevalue",
 			wantErr: true,
 		},
 		{
-			name:    "missing name",
-			input:   "=value",
-			wantErr: true,
+			name:  "no name",
+			input: "=value",
+			expected: []*FormPart{
+				{Name: "", Value: "value", Type: PartTypeLiteral, Filename: "value"},
+			},
+		},
+		{
+			name:  "multipart",
+			input: "mail=(;type=multipart/alternative",
+			expected: []*FormPart{
+				{Name: "mail", Type: PartTypeMultipart, ContentType: "multipart/alternative"},
+			},
+		},
+		{
+			name:  "encoder",
+			input: "text=<body.txt;encoder=quoted-printable",
+			expected: []*FormPart{
+				{Name: "text", Value: "body.txt", Type: PartTypeDataFile, Filename: "body.txt", Encoder: "quoted-printable"},
+			},
 		},
 		{
 			name:    "literal with comma",
@@ -106,4 +132,24 @@ func TestParseFormString(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestParseFormString_HeadersFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "headers")
+	content := "# comment\nX-First: one\nX-Folded: two\n  three\n\nX-Last: four\n"
+	if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	parts, err := ParseFormString("name=value;headers=@" + file + ";headers=X-Inline: five")
+	if err != nil {
+		t.Fatalf("ParseFormString() failed: %v", err)
+	}
+	want := []string{"X-First: one", "X-Folded: two three", "X-Last: four", "X-Inline: five"}
+	if !reflect.DeepEqual(parts[0].Headers, want) {
+		t.Errorf("Headers = %q; want %q", parts[0].Headers, want)
+	}
+
+	if _, err := ParseFormString("name=value;headers=@" + filepath.Join(t.TempDir(), "nope")); err == nil {
+		t.Error("ParseFormString() should fail for a missing headers file")
+	}
+}
@@ -0,0 +1,373 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// This file contains the FTP downloads, the part of libcurl's lib/ftp.c
+// the tool needs for ftp:// URLs: the login, the walk to the directory of
+// the file, passive mode data connections, and RETR with REST for -r and
+// -C. A URL ending with a slash lists the directory.
+
+// ftpConn is the control connection of an FTP transfer.
+type ftpConn struct {
+	t    *Transfer
+	conn net.Conn
+	text *textproto.Conn
+	code int // the code of the last reply
+}
+
+// performFTP downloads the file named by the ftp:// URL u. Uploads are not
+// implemented, and fail with CurlNotBuiltIn as a feature libcurl was built
+// without would.
+func (t *Transfer) performFTP(ctx context.Context, u *url.URL) *TransferError {
+	if t.Infile != "" {
+		return newTransferError(CurlNotBuiltIn, "FTP uploads are not supported")
+	}
+	t.Info["url_effective"] = t.URL
+	t.Info["scheme"] = "ftp"
+
+	c, terr := t.dialFTP(ctx)
+	if terr != nil {
+		return terr
+	}
+	defer c.close()
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+
+	terr = c.run(ctx, u)
+	t.Info["response_code"] = int64(c.code)
+	if terr != nil && ctx.Err() != nil {
+		return t.classifyError(ctx.Err())
+	}
+	return terr
+}
+
+// dialFTP connects to the server and reads its greeting.
+func (t *Transfer) dialFTP(ctx context.Context) (*ftpConn, *TransferError) {
+	dialer := &net.Dialer{Timeout: t.Config.ConnectTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(t.host, t.port))
+	if err != nil {
+		return nil, t.classifyError(err)
+	}
+	t.numConnects++
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		t.trace(InfoTypeText, fmt.Sprintf("Connected to %s port %d\n", addr.IP, addr.Port))
+	}
+
+	c := &ftpConn{t: t, conn: conn, text: textproto.NewConn(conn)}
+	code, _, terr := c.reply()
+	if terr != nil {
+		conn.Close()
+		return nil, terr
+	}
+	if code != 220 {
+		conn.Close()
+		return nil, newTransferError(CurlWeirdServerReply, "Got a %03d ftp-server response when 220 was expected", code)
+	}
+	return c, nil
+}
+
+// reply reads a reply from the server and returns its code and message.
+func (c *ftpConn) reply() (int, string, *TransferError) {
+	code, msg, err := c.text.ReadResponse(0)
+	if err != nil {
+		return 0, "", c.t.classifyError(err)
+	}
+	c.code = code
+	for _, line := range strings.Split(msg, "\n") {
+		c.t.trace(InfoTypeHeaderIn, fmt.Sprintf("%03d %s\r\n", code, line))
+	}
+	return code, msg, nil
+}
+
+// cmd sends a command and returns the code of the reply along with its
+// message.
+func (c *ftpConn) cmd(format string, args ...interface{}) (int, string, *TransferError) {
+	line := fmt.Sprintf(format, args...)
+	c.t.trace(InfoTypeHeaderOut, line+"\r\n")
+	if err := c.text.PrintfLine("%s", line); err != nil {
+		return 0, "", c.t.classifyError(err)
+	}
+	return c.reply()
+}
+
+// close ends the session, ignoring the server's answer.
+func (c *ftpConn) close() {
+	c.t.trace(InfoTypeHeaderOut, "QUIT\r\n")
+	if c.text.PrintfLine("QUIT") == nil {
+		c.text.ReadResponse(0)
+	}
+	c.conn.Close()
+}
+
+// run logs in and performs the transfer.
+func (c *ftpConn) run(ctx context.Context, u *url.URL) *TransferError {
+	if terr := c.login(u); terr != nil {
+		return terr
+	}
+
+	dir, file := path.Split(u.Path)
+	for _, elem := range strings.Split(strings.Trim(dir, "/"), "/") {
+		if elem == "" {
+			continue
+		}
+		code, _, terr := c.cmd("CWD %s", elem)
+		if terr != nil {
+			return terr
+		}
+		if code/100 != 2 {
+			return newTransferError(CurlRemoteAccessDenied, "Server denied you to change to the given directory")
+		}
+	}
+
+	if file == "" {
+		return c.list(ctx, u)
+	}
+	return c.retrieve(ctx, u, file)
+}
+
+// login sends the user name and password: those of -u, else those of the
+// URL, else the anonymous login.
+func (c *ftpConn) login(u *url.URL) *TransferError {
+	user, pass := "anonymous", "ftp@example.com"
+	if c.t.Config.UserPassword != "" {
+		user, pass, _ = strings.Cut(c.t.Config.UserPassword, ":")
+	} else if u.User != nil {
+		user = u.User.Username()
+		pass, _ = u.User.Password()
+	}
+
+	code, _, terr := c.cmd("USER %s", user)
+	if terr != nil {
+		return terr
+	}
+	if code == 331 {
+		if code, _, terr = c.cmd("PASS %s", pass); terr != nil {
+			return terr
+		}
+	}
+	switch {
+	case code == 230:
+		return nil
+	case code == 530:
+		return newTransferError(CurlLoginDenied, "Access denied: %03d", code)
+	default:
+		return newTransferError(CurlWeirdServerReply, "Got a %03d response code instead of the assumed 230", code)
+	}
+}
+
+// passive opens a data connection, asking for the port with EPSV and
+// falling back to PASV. Like curl's default --ftp-skip-pasv-ip, the host
+// of the control connection is used whatever address PASV returns.
+func (c *ftpConn) passive(ctx context.Context) (net.Conn, *TransferError) {
+	var port int
+	code, msg, terr := c.cmd("EPSV")
+	if terr != nil {
+		return nil, terr
+	}
+	if code == 229 {
+		// 229 Entering Extended Passive Mode (|||port|)
+		_, rest, ok := strings.Cut(msg, "(|||")
+		digits, _, ok2 := strings.Cut(rest, "|")
+		var err error
+		if port, err = strconv.Atoi(digits); !ok || !ok2 || err != nil {
+			return nil, newTransferError(CurlFTPWeirdPasvReply, "Weirdly formatted EPSV reply")
+		}
+	} else {
+		if code, msg, terr = c.cmd("PASV"); terr != nil {
+			return nil, terr
+		}
+		if code != 227 {
+			return nil, newTransferError(CurlFTPWeirdPasvReply, "Bad PASV/EPSV response: %03d", code)
+		}
+		// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2)
+		_, rest, _ := strings.Cut(msg, "(")
+		rest, _, _ = strings.Cut(rest, ")")
+		fields := strings.Split(rest, ",")
+		if len(fields) != 6 {
+			return nil, newTransferError(CurlFTPWeirdPasvReply, "Couldn't interpret the 227-response")
+		}
+		p1, err1 := strconv.Atoi(strings.TrimSpace(fields[4]))
+		p2, err2 := strconv.Atoi(strings.TrimSpace(fields[5]))
+		if err1 != nil || err2 != nil {
+			return nil, newTransferError(CurlFTPWeirdPasvReply, "Couldn't interpret the 227-response")
+		}
+		port = p1<<8 | p2
+	}
+
+	host, _, _ := net.SplitHostPort(c.conn.RemoteAddr().String())
+	dialer := &net.Dialer{Timeout: c.t.Config.ConnectTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, c.t.classifyError(err)
+	}
+	return conn, nil
+}
+
+// list outputs the listing of the current directory.
+func (c *ftpConn) list(ctx context.Context, u *url.URL) *TransferError {
+	if code, _, terr := c.cmd("TYPE A"); terr != nil {
+		return terr
+	} else if code/100 != 2 {
+		return newTransferError(CurlFTPCouldntSetType, "Couldn't set desired mode")
+	}
+	data, terr := c.passive(ctx)
+	if terr != nil {
+		return terr
+	}
+	defer data.Close()
+	code, _, terr := c.cmd("LIST")
+	if terr != nil {
+		return terr
+	}
+	if code != 125 && code != 150 {
+		return newTransferError(CurlRemoteFileNotFound, "Given directory does not exist")
+	}
+
+	name, terr := c.t.outputName(u.Path, "")
+	if terr != nil {
+		return terr
+	}
+	out, terr := c.t.openOutput(name, false)
+	if terr != nil {
+		return terr
+	}
+	defer out.Close()
+	c.t.Info["size_download"] = int64(0)
+	if terr := c.t.copyBody(out, out.w, data); terr != nil {
+		return terr
+	}
+	return c.finish(data, false)
+}
+
+// retrieve downloads file, or the parts of it selected by -r or -C, each
+// with a RETR starting at the offset set with REST.
+func (c *ftpConn) retrieve(ctx context.Context, u *url.URL, file string) *TransferError {
+	t := c.t
+	if code, _, terr := c.cmd("TYPE I"); terr != nil {
+		return terr
+	} else if code/100 != 2 {
+		return newTransferError(CurlFTPCouldntSetType, "Couldn't set desired mode")
+	}
+
+	size := int64(-1)
+	code, msg, terr := c.cmd("SIZE %s", file)
+	if terr != nil {
+		return terr
+	}
+	if code == 213 {
+		if n, err := strconv.ParseInt(strings.TrimSpace(msg), 10, 64); err == nil {
+			size = n
+		}
+	}
+	if t.Config.NoBody {
+		return nil
+	}
+
+	ranges, terr := t.localRanges(size)
+	if terr != nil {
+		return terr
+	}
+	var total int64
+	for _, r := range ranges {
+		total += max(r.Len(), 0)
+	}
+	t.dlTotal.Store(total)
+	t.Info["size_download"] = int64(0)
+
+	name, terr := t.outputName(u.Path, "")
+	if terr != nil {
+		return terr
+	}
+	var out *transferOutput
+	defer func() {
+		if out != nil {
+			out.Close()
+		}
+	}()
+
+	for _, r := range ranges {
+		if r.Len() == 0 {
+			continue
+		}
+		data, terr := c.passive(ctx)
+		if terr != nil {
+			return terr
+		}
+		terr = c.retrieveRange(data, file, r, name, &out)
+		data.Close()
+		if terr != nil {
+			return terr
+		}
+	}
+	if out == nil {
+		// Nothing was left to download, but the output still exists.
+		if out, terr = t.openOutput(name, false); terr != nil {
+			return terr
+		}
+	}
+	return nil
+}
+
+// retrieveRange reads the range r of file over the data connection,
+// opening the output named name into *out first if it is not open yet.
+func (c *ftpConn) retrieveRange(data net.Conn, file string, r byteRange, name string, out **transferOutput) *TransferError {
+	t := c.t
+	if r.Start > 0 {
+		code, _, terr := c.cmd("REST %d", r.Start)
+		if terr != nil {
+			return terr
+		}
+		if code != 350 {
+			return newTransferError(CurlFTPCouldntUseRest, "Couldn't use REST")
+		}
+	}
+	code, _, terr := c.cmd("RETR %s", file)
+	if terr != nil {
+		return terr
+	}
+	switch {
+	case code == 550:
+		return newTransferError(CurlRemoteFileNotFound, "The file does not exist")
+	case code != 125 && code != 150:
+		return newTransferError(CurlFTPCouldntRetrFile, "RETR response: %03d", code)
+	}
+
+	if *out == nil {
+		if *out, terr = t.openOutput(name, false); terr != nil {
+			return terr
+		}
+	}
+	var src io.Reader = data
+	if r.Len() >= 0 {
+		src = io.LimitReader(data, r.Len())
+	}
+	if terr := t.copyBody(*out, t.rangeWriter(*out, r.Start), src); terr != nil {
+		return terr
+	}
+	return c.finish(data, r.Len() >= 0)
+}
+
+// finish closes the data connection and reads the reply that ends the
+// transfer. When the download stopped before the end of the file, the
+// server may report the transfer as aborted, which is expected.
+func (c *ftpConn) finish(data net.Conn, cut bool) *TransferError {
+	data.Close()
+	code, _, terr := c.reply()
+	if terr != nil {
+		return terr
+	}
+	if code/100 == 2 || cut && (code == 426 || code == 451) {
+		return nil
+	}
+	return newTransferError(CurlPartialFile, "Transfer ended with response %03d", code)
+}
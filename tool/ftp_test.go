@@ -0,0 +1,279 @@
+package tool
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeFTPServer is a minimal FTP server serving files from memory, enough
+// for the commands sent by performFTP.
+type fakeFTPServer struct {
+	ln     net.Listener
+	files  map[string]string // contents by absolute path
+	noEPSV bool
+
+	mu       sync.Mutex
+	commands []string
+}
+
+func newFakeFTPServer(t *testing.T, files map[string]string) *fakeFTPServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeFTPServer{ln: ln, files: files}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeFTPServer) URL() string {
+	return "ftp://" + s.ln.Addr().String()
+}
+
+// Commands returns the commands received, without the login.
+func (s *fakeFTPServer) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var cmds []string
+	for _, c := range s.commands {
+		if !strings.HasPrefix(c, "USER") && !strings.HasPrefix(c, "PASS") {
+			cmds = append(cmds, c)
+		}
+	}
+	return cmds
+}
+
+func (s *fakeFTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(format string, args ...interface{}) {
+		fmt.Fprintf(conn, format+"\r\n", args...)
+	}
+	reply("220 fake server ready")
+
+	cwd := "/"
+	var rest int64
+	var data net.Listener
+	defer func() {
+		if data != nil {
+			data.Close()
+		}
+	}()
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+		cmd, arg, _ := strings.Cut(line, " ")
+
+		switch cmd {
+		case "USER":
+			reply("331 password please")
+		case "PASS":
+			if arg == "wrong" {
+				reply("530 login incorrect")
+			} else {
+				reply("230 logged in")
+			}
+		case "CWD":
+			dir := filepath.Join(cwd, arg)
+			found := false
+			for name := range s.files {
+				if strings.HasPrefix(name, dir+"/") {
+					found = true
+				}
+			}
+			if !found {
+				reply("550 no such directory")
+				continue
+			}
+			cwd = dir
+			reply("250 ok")
+		case "TYPE":
+			reply("200 type set")
+		case "SIZE":
+			content, ok := s.files[filepath.Join(cwd, arg)]
+			if !ok {
+				reply("550 no such file")
+				continue
+			}
+			reply("213 %d", len(content))
+		case "EPSV", "PASV":
+			if cmd == "EPSV" && s.noEPSV {
+				reply("500 unknown command")
+				continue
+			}
+			if data, err = net.Listen("tcp", "127.0.0.1:0"); err != nil {
+				reply("425 cannot open")
+				continue
+			}
+			port := data.Addr().(*net.TCPAddr).Port
+			if cmd == "EPSV" {
+				reply("229 Entering Extended Passive Mode (|||%d|)", port)
+			} else {
+				reply("227 Entering Passive Mode (10,0,0,1,%d,%d)", port>>8, port&0xff)
+			}
+		case "REST":
+			rest, _ = strconv.ParseInt(arg, 10, 64)
+			reply("350 restarting")
+		case "RETR", "LIST":
+			var content string
+			if cmd == "RETR" {
+				c, ok := s.files[filepath.Join(cwd, arg)]
+				if !ok {
+					reply("550 no such file")
+					continue
+				}
+				content = c[min(rest, int64(len(c))):]
+			} else {
+				var names []string
+				for name := range s.files {
+					if filepath.Dir(name) == cwd {
+						names = append(names, filepath.Base(name))
+					}
+				}
+				sort.Strings(names)
+				content = strings.Join(names, "\r\n") + "\r\n"
+			}
+			rest = 0
+			reply("150 opening data connection")
+			dc, err := data.Accept()
+			data.Close()
+			data = nil
+			if err != nil {
+				reply("425 no data connection")
+				continue
+			}
+			_, err = dc.Write([]byte(content))
+			dc.Close()
+			if err != nil {
+				reply("426 transfer aborted")
+			} else {
+				reply("226 transfer complete")
+			}
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestPerformFTP(t *testing.T) {
+	dir := isolateCurlRC(t)
+	srv := newFakeFTPServer(t, map[string]string{
+		"/pub/file.txt":  "hello world",
+		"/pub/other.txt": "other",
+	})
+
+	testCases := []struct {
+		args []string
+		want string
+		cmds []string
+	}{
+		{nil, "hello world", []string{"CWD pub", "TYPE I", "SIZE file.txt", "EPSV", "RETR file.txt", "QUIT"}},
+		{[]string{"-r", "6-"}, "world", []string{"CWD pub", "TYPE I", "SIZE file.txt", "EPSV", "REST 6", "RETR file.txt", "QUIT"}},
+		{[]string{"-r", "0-4"}, "hello", []string{"CWD pub", "TYPE I", "SIZE file.txt", "EPSV", "RETR file.txt", "QUIT"}},
+		{[]string{"-r", "-3"}, "rld", []string{"CWD pub", "TYPE I", "SIZE file.txt", "EPSV", "REST 8", "RETR file.txt", "QUIT"}},
+		{[]string{"-C", "6"}, "world", []string{"CWD pub", "TYPE I", "SIZE file.txt", "EPSV", "REST 6", "RETR file.txt", "QUIT"}},
+	}
+	for _, tc := range testCases {
+		srv := newFakeFTPServer(t, srv.files)
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, append(tc.args, srv.URL()+"/pub/file.txt")); err != nil {
+			t.Errorf("Operate(%q) failed: %v", tc.args, err)
+			continue
+		}
+		if stdout.String() != tc.want {
+			t.Errorf("Operate(%q) output = %q; want %q", tc.args, stdout.String(), tc.want)
+		}
+		if got := strings.Join(srv.Commands(), ", "); got != strings.Join(tc.cmds, ", ") {
+			t.Errorf("Operate(%q) commands = %s; want %s", tc.args, got, strings.Join(tc.cmds, ", "))
+		}
+	}
+
+	t.Run("PASV and listing", func(t *testing.T) {
+		srv := newFakeFTPServer(t, srv.files)
+		srv.noEPSV = true
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{srv.URL() + "/pub/"}); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if stdout.String() != "file.txt\r\nother.txt\r\n" {
+			t.Errorf("listing = %q", stdout.String())
+		}
+	})
+
+	t.Run("resume", func(t *testing.T) {
+		out := filepath.Join(dir, "out.txt")
+		if err := os.WriteFile(out, []byte("hello"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		global, _, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{"-C", "-", "-o", out, srv.URL() + "/pub/file.txt"}); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if got, _ := os.ReadFile(out); string(got) != "hello world" {
+			t.Errorf("file = %q; want %q", got, "hello world")
+		}
+	})
+
+	t.Run("upload", func(t *testing.T) {
+		upload := filepath.Join(dir, "up.txt")
+		if err := os.WriteFile(upload, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		srv := newFakeFTPServer(t, srv.files)
+		global, _, _ := newTestGlobal()
+		err := Operate(context.Background(), global, []string{"-T", upload, srv.URL() + "/pub/"})
+		if code := ErrorCode(err); code != CurlNotBuiltIn {
+			t.Errorf("ErrorCode(Operate()) = %d; want %d", code, CurlNotBuiltIn)
+		}
+		if err == nil || !strings.Contains(err.Error(), "FTP uploads are not supported") {
+			t.Errorf("Operate() error = %v; want FTP uploads are not supported", err)
+		}
+		if cmds := srv.Commands(); len(cmds) != 0 {
+			t.Errorf("commands = %q; want none", cmds)
+		}
+	})
+
+	errorCases := []struct {
+		args []string
+		want CurlCode
+	}{
+		{[]string{srv.URL() + "/pub/missing.txt"}, CurlRemoteFileNotFound},
+		{[]string{srv.URL() + "/nodir/file.txt"}, CurlRemoteAccessDenied},
+		{[]string{"-u", "user:wrong", srv.URL() + "/pub/file.txt"}, CurlLoginDenied},
+		{[]string{"-r", "20-", srv.URL() + "/pub/file.txt"}, CurlBadDownloadResume},
+	}
+	for _, tc := range errorCases {
+		global, _, _ := newTestGlobal()
+		if code := ErrorCode(Operate(context.Background(), global, tc.args)); code != tc.want {
+			t.Errorf("Operate(%q) code = %d; want %d", tc.args, code, tc.want)
+		}
+	}
+}
@@ -1,22 +1,26 @@
 package tool
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math"
+	"os"
 	"runtime"
 	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 // ArgType defines the type of argument an option expects.
 type ArgType int
 
 const (
-	ArgNone ArgType = iota // Stand-alone option
-	ArgBool                // Boolean option (e.g., --verbose, --no-verbose)
-	ArgString              // Option requires a string argument
-	ArgFile                // Option requires a file path argument
+	ArgNone   ArgType = iota // Stand-alone option
+	ArgBool                  // Boolean option (e.g., --verbose, --no-verbose)
+	ArgString                // Option requires a string argument
+	ArgFile                  // Option requires a file path argument
 )
 
 // Option defines a single command-line option.
@@ -30,33 +34,71 @@ type Option struct {
 
 // options is a map of all supported command-line options.
 var options = map[string]Option{
-	"url":             {Name: "url", Type: ArgString, Handler: handleURL},
-	"verbose":         {Name: "verbose", ShortName: 'v', Type: ArgBool, Handler: handleVerbose},
-	"header":          {Name: "header", ShortName: 'H', Type: ArgString, Handler: handleHeader},
-	"data":            {Name: "data", ShortName: 'd', Type: ArgString, Handler: handleData},
-	"request":         {Name: "request", ShortName: 'X', Type: ArgString, Handler: handleString("CustomRequest")},
-	"user-agent":      {Name: "user-agent", ShortName: 'A', Type: ArgString, Handler: handleString("UserAgent")},
-	"insecure":        {Name: "insecure", ShortName: 'k', Type: ArgBool, Handler: handleBool("InsecureOK")},
-	"location":        {Name: "location", ShortName: 'L', Type: ArgBool, Handler: handleBool("FollowLocation")},
-	"output":          {Name: "output", ShortName: 'o', Type: ArgFile, Handler: handleOutputFile},
-	"remote-name":     {Name: "remote-name", ShortName: 'O', Type: ArgBool, Handler: handleRemoteName},
-	"user":            {Name: "user", ShortName: 'u', Type: ArgString, Handler: handleString("UserPassword")},
-	"head":            {Name: "head", ShortName: 'I', Type: ArgBool, Handler: handleHead},
-	"get":             {Name: "get", ShortName: 'G', Type: ArgBool, Handler: handleBool("UseHTTPGet")},
-	"connect-timeout": {Name: "connect-timeout", Type: ArgString, Handler: handleConnectTimeout},
-	"fail":            {Name: "fail", ShortName: 'f', Type: ArgBool, Handler: handleBool("FailOnError")},
-	"range":           {Name: "range", ShortName: 'r', Type: ArgString, Handler: handleRange},
+	"url":                {Name: "url", Type: ArgString, Handler: handleURL},
+	"verbose":            {Name: "verbose", ShortName: 'v', Type: ArgBool, Handler: handleVerbose},
+	"header":             {Name: "header", ShortName: 'H', Type: ArgString, Handler: handleHeader},
+	"data":               {Name: "data", ShortName: 'd', Type: ArgString, Handler: handleData(dataASCII)},
+	"data-ascii":         {Name: "data-ascii", Type: ArgString, Handler: handleData(dataASCII)},
+	"data-binary":        {Name: "data-binary", Type: ArgString, Handler: handleData(dataBinary)},
+	"data-raw":           {Name: "data-raw", Type: ArgString, Handler: handleData(dataRaw)},
+	"data-urlencode":     {Name: "data-urlencode", Type: ArgString, Handler: handleData(dataURLEncode)},
+	"json":               {Name: "json", Type: ArgString, Handler: handleData(dataJSON)},
+	"url-query":          {Name: "url-query", Type: ArgString, Handler: handleURLQuery},
+	"form":               {Name: "form", ShortName: 'F', Type: ArgString, Handler: handleForm(false)},
+	"form-string":        {Name: "form-string", Type: ArgString, Handler: handleForm(true)},
+	"form-escape":        {Name: "form-escape", Type: ArgBool, Handler: handleBool("FormEscape")},
+	"upload-file":        {Name: "upload-file", ShortName: 'T', Type: ArgString, Handler: handleUploadFile},
+	"request":            {Name: "request", ShortName: 'X', Type: ArgString, Handler: handleString("CustomRequest")},
+	"user-agent":         {Name: "user-agent", ShortName: 'A', Type: ArgString, Handler: handleString("UserAgent")},
+	"insecure":           {Name: "insecure", ShortName: 'k', Type: ArgBool, Handler: handleBool("InsecureOK")},
+	"location":           {Name: "location", ShortName: 'L', Type: ArgBool, Handler: handleBool("FollowLocation")},
+	"output":             {Name: "output", ShortName: 'o', Type: ArgFile, Handler: handleOutputFile},
+	"remote-name":        {Name: "remote-name", ShortName: 'O', Type: ArgBool, Handler: handleRemoteName},
+	"user":               {Name: "user", ShortName: 'u', Type: ArgString, Handler: handleString("UserPassword")},
+	"head":               {Name: "head", ShortName: 'I', Type: ArgBool, Handler: handleHead},
+	"get":                {Name: "get", ShortName: 'G', Type: ArgBool, Handler: handleBool("UseHTTPGet")},
+	"globoff":            {Name: "globoff", ShortName: 'g', Type: ArgBool, Handler: handleGlobOff},
+	"connect-timeout":    {Name: "connect-timeout", Type: ArgString, Handler: handleConnectTimeout},
+	"fail":               {Name: "fail", ShortName: 'f', Type: ArgBool, Handler: handleBool("FailOnError")},
+	"range":              {Name: "range", ShortName: 'r', Type: ArgString, Handler: handleRange},
+	"segments":           {Name: "segments", Type: ArgString, Handler: handleSegments},
+	"sparse-ranges":      {Name: "sparse-ranges", Type: ArgBool, Handler: handleBool("SparseRanges")},
+	"continue-at":        {Name: "continue-at", ShortName: 'C', Type: ArgString, Handler: handleContinueAt},
+	"referer":            {Name: "referer", ShortName: 'e', Type: ArgString, Handler: handleString("Referer")},
+	"proxy":              {Name: "proxy", ShortName: 'x', Type: ArgString, Handler: handleString("Proxy")},
+	"proxy-user":         {Name: "proxy-user", ShortName: 'U', Type: ArgString, Handler: handleString("ProxyUserPassword")},
+	"max-redirs":         {Name: "max-redirs", Type: ArgString, Handler: handleMaxRedirs},
+	"include":            {Name: "include", ShortName: 'i', Type: ArgBool, Handler: handleBool("ShowHeaders")},
+	"dump-header":        {Name: "dump-header", ShortName: 'D', Type: ArgFile, Handler: handleString("HeaderFile")},
+	"write-out":          {Name: "write-out", ShortName: 'w', Type: ArgString, Handler: handleWriteOut},
+	"remote-header-name": {Name: "remote-header-name", ShortName: 'J', Type: ArgBool, Handler: handleBool("ContentDisposition")},
+	"remote-time":        {Name: "remote-time", ShortName: 'R', Type: ArgBool, Handler: handleBool("RemoteTime")},
+	"silent":             {Name: "silent", ShortName: 's', Type: ArgBool, Handler: handleSilent},
+	"show-error":         {Name: "show-error", ShortName: 'S', Type: ArgBool, Handler: handleShowError},
+	"max-time":           {Name: "max-time", ShortName: 'm', Type: ArgString, Handler: handleMaxTime},
+	"parallel":           {Name: "parallel", ShortName: 'Z', Type: ArgBool, Handler: handleGlobalBool("Parallel")},
+	"parallel-max":       {Name: "parallel-max", Type: ArgString, Handler: handleParallelMax},
+	"parallel-immediate": {Name: "parallel-immediate", Type: ArgBool, Handler: handleGlobalBool("ParallelConnect")},
+	"fail-early":         {Name: "fail-early", Type: ArgBool, Handler: handleGlobalBool("FailEarly")},
+	"next":               {Name: "next", ShortName: ':', Type: ArgNone, Handler: handleNext},
+	"disable":            {Name: "disable", ShortName: 'q', Type: ArgBool, Handler: handleDisable},
+	"variable":           {Name: "variable", Type: ArgString, Handler: handleVariable},
 	// Auth options
-	"anyauth":   {Name: "anyauth", Type: ArgBool, Handler: handleAuth(AuthAny)},
-	"basic":     {Name: "basic", Type: ArgBool, Handler: handleAuth(AuthBasic)},
-	"digest":    {Name: "digest", Type: ArgBool, Handler: handleAuth(AuthDigest)},
-	"ntlm":      {Name: "ntlm", Type: ArgBool, Handler: handleAuth(AuthNTLM)},
+	"anyauth": {Name: "anyauth", Type: ArgBool, Handler: handleAuth(AuthAny)},
+	"basic":   {Name: "basic", Type: ArgBool, Handler: handleAuth(AuthBasic)},
+	"digest":  {Name: "digest", Type: ArgBool, Handler: handleAuth(AuthDigest)},
+	"ntlm":    {Name: "ntlm", Type: ArgBool, Handler: handleAuth(AuthNTLM)},
 }
 
 // shortOptions is a reverse map for finding long options by their short name.
 var shortOptions = make(map[rune]Option)
 
 func init() {
+	// --config is added here rather than in the map literal because its
+	// handler parses options itself, which would make the initialization
+	// of options refer to itself.
+	options["config"] = Option{Name: "config", ShortName: 'K', Type: ArgFile, Handler: handleConfig}
+
 	for name, opt := range options {
 		if opt.ShortName != 0 {
 			// Add a reference back to the long name for consistency
@@ -67,9 +109,88 @@ func init() {
 	}
 }
 
+// OptionError is the error returned by the parser when an option cannot be
+// applied. Code is the C `ParameterError` the failure maps to, Option the
+// flag as it was given and Arg the argument it was applied with, if any.
+// Detail optionally explains the failure further, like the separate errorf
+// message the C code prints before some of these errors.
+//
+// File and Line are set when the option was read from a config file.
+type OptionError struct {
+	Code   ParameterError
+	Option string
+	Arg    string
+	Detail string
+	File   string
+	Line   int
+}
+
+// Error formats the error the way curl reports it, e.g.
+// "option --foo: is unknown", prefixed with "file:line: " for an option
+// from a config file.
+func (e *OptionError) Error() string {
+	text := e.Code.String()
+	if e.Option != "" {
+		text = fmt.Sprintf("option %s: %s", e.Option, text)
+	}
+	if e.File != "" {
+		text = fmt.Sprintf("%s:%d: %s", e.File, e.Line, text)
+	}
+	return text
+}
+
+// Unwrap returns the ParameterError code so that errors.Is(err, ParamBadUse)
+// works on an *OptionError.
+func (e *OptionError) Unwrap() error {
+	return e.Code
+}
+
+// paramErrorf returns an OptionError with the given code and detail
+// message. Handlers use it when a bare ParameterError would not tell the
+// user enough; the parser fills in the option and argument.
+func paramErrorf(code ParameterError, format string, args ...interface{}) *OptionError {
+	return &OptionError{Code: code, Detail: fmt.Sprintf(format, args...)}
+}
+
+// toOptionError turns an error from an option handler into an
+// *OptionError. Handlers return either a ParameterError or an OptionError;
+// anything else is reported as a bad use of the option.
+func toOptionError(err error) *OptionError {
+	var oe *OptionError
+	if errors.As(err, &oe) {
+		copied := *oe
+		return &copied
+	}
+	var code ParameterError
+	if errors.As(err, &code) {
+		return &OptionError{Code: code}
+	}
+	return &OptionError{Code: ParamBadUse, Detail: err.Error()}
+}
+
+// numericError returns the error for a number argument that failed to
+// parse: ParamNegativeNumeric for a negative one, ParamBadNumeric otherwise.
+func numericError(arg string) ParameterError {
+	if strings.HasPrefix(strings.TrimSpace(arg), "-") {
+		return ParamNegativeNumeric
+	}
+	return ParamBadNumeric
+}
+
 // ParameterParser holds the state for parsing arguments.
 type ParameterParser struct {
 	Global *GlobalConfig
+
+	// toggle is the value an ArgBool handler applies: false when the option
+	// was given with a --no- prefix, true otherwise.
+	toggle bool
+
+	// expand is set when the option was given with an --expand- prefix,
+	// so that variables in its argument are expanded.
+	expand bool
+
+	// configFiles holds the config files being read, innermost last.
+	configFiles []string
 }
 
 // NewParameterParser creates a new parser.
@@ -96,7 +217,7 @@ func (p *ParameterParser) Parse(args []string) error {
 
 			usedArg, err := p.ParseOne(arg, nextArg)
 			if err != nil {
-				return fmt.Errorf("option %s: %w", arg, err)
+				return err
 			}
 			if usedArg {
 				i++ // The next argument was consumed
@@ -112,30 +233,78 @@ func (p *ParameterParser) Parse(args []string) error {
 	return nil
 }
 
-// ParseOne parses a single flag and its potential argument.
+// ParseOne parses a single flag and its potential argument. A short flag
+// may bundle several options, as in "-sSfL": each boolean option is applied
+// in turn, and the first option that takes an argument uses the rest of the
+// flag as its argument, or nextarg when nothing is left ("-vko out.txt").
+// Failures are returned as an *OptionError.
 func (p *ParameterParser) ParseOne(flag, nextarg string) (usedArg bool, err error) {
-	var opt Option
-	var ok bool
-	var arg string
-
-	isLongOpt := strings.HasPrefix(flag, "--")
-	if isLongOpt {
-		optName := strings.TrimPrefix(flag, "--")
-		opt, ok = options[optName]
-		if !ok {
-			return false, fmt.Errorf("unknown option")
+	usedArg, err = p.parseFlag(flag, nextarg)
+	if err != nil {
+		oe := toOptionError(err)
+		if oe.Option == "" && oe.File == "" {
+			// Errors from a config file loaded by this flag keep the
+			// position and option at fault.
+			oe.Option = flag
 		}
-	} else { // Short option
-		shortName := rune(flag[1])
-		opt, ok = shortOptions[shortName]
+		return false, oe
+	}
+	if config := p.Global.Last; config.ContentDisposition && config.ResumeFromCurrent {
+		// The name of the file to resume is not known before the
+		// transfer when the server provides it.
+		return false, &OptionError{Code: ParamContDispResumeFrom, Option: flag}
+	}
+	return usedArg, nil
+}
+
+// parseFlag does the work of ParseOne.
+func (p *ParameterParser) parseFlag(flag, nextarg string) (usedArg bool, err error) {
+	p.toggle = true
+	p.expand = false
+	if strings.HasPrefix(flag, "--") {
+		name := strings.TrimPrefix(flag, "--")
+		if expanded, ok := strings.CutPrefix(name, "expand-"); ok && expanded != "" {
+			if _, err := lookupLongOption(name); err != nil {
+				name = expanded
+				p.expand = true
+			}
+		}
+		opt, toggle, err := findLongOption(name)
+		if err != nil {
+			return false, err
+		}
+		if p.expand && (opt.Type == ArgBool || opt.Type == ArgNone || !toggle) {
+			// Only an argument can be expanded.
+			return false, ParamExpandError
+		}
+		p.toggle = toggle
+		return p.apply(opt, "", nextarg)
+	}
+
+	letters := strings.TrimPrefix(flag, "-")
+	if letters == "" {
+		return false, ParamOptionUnknown
+	}
+	for i, shortName := range letters {
+		opt, ok := shortOptions[shortName]
 		if !ok {
-			return false, fmt.Errorf("unknown option")
+			return false, ParamOptionUnknown
+		}
+		if opt.Type == ArgString || opt.Type == ArgFile {
+			rest := letters[i+utf8.RuneLen(shortName):]
+			return p.apply(opt, rest, nextarg)
 		}
-		if len(flag) > 2 {
-			arg = flag[2:]
+		if _, err := p.apply(opt, "", ""); err != nil {
+			return false, err
 		}
 	}
+	return false, nil
+}
 
+// apply runs the handler of a resolved option. For options that take an
+// argument, arg is the part bundled with the flag itself; when it is empty
+// the argument is taken from nextarg, which is then reported as used.
+func (p *ParameterParser) apply(opt Option, arg, nextarg string) (usedArg bool, err error) {
 	if opt.Type == ArgString || opt.Type == ArgFile {
 		if arg != "" {
 			// Argument was bundled
@@ -143,7 +312,14 @@ func (p *ParameterParser) ParseOne(flag, nextarg string) (usedArg bool, err erro
 			arg = nextarg
 			usedArg = true
 		} else {
-			return false, fmt.Errorf("requires an argument")
+			return false, ParamRequiresParameter
+		}
+		if p.expand {
+			if arg, err = expandVariables(p.Global, arg); err != nil {
+				oe := toOptionError(err)
+				oe.Arg = nextarg
+				return false, oe
+			}
 		}
 	}
 
@@ -155,12 +331,57 @@ func (p *ParameterParser) ParseOne(flag, nextarg string) (usedArg bool, err erro
 	// as not "used", so it can be processed later (e.g., as a URL).
 	if err != nil {
 		// We return usedArg=false because the argument was not successfully consumed.
-		return false, err
+		oe := toOptionError(err)
+		if oe.Option == "" && oe.File == "" {
+			oe.Arg = arg
+		}
+		return false, oe
 	}
 
 	return usedArg, err
 }
 
+// findLongOption looks up a long option name the way curl does. An exact
+// match wins; otherwise the name may be an abbreviation of exactly one
+// option. A "no-" prefix on a boolean option negates it, which is reported
+// by returning toggle as false.
+func findLongOption(name string) (opt Option, toggle bool, err error) {
+	opt, err = lookupLongOption(name)
+	if err == nil || !strings.HasPrefix(name, "no-") {
+		return opt, true, err
+	}
+	opt, err = lookupLongOption(strings.TrimPrefix(name, "no-"))
+	if err != nil {
+		return opt, false, err
+	}
+	if opt.Type != ArgBool {
+		return opt, false, ParamNoPrefix
+	}
+	return opt, false, nil
+}
+
+// lookupLongOption resolves a full or abbreviated long option name.
+func lookupLongOption(name string) (Option, error) {
+	if opt, ok := options[name]; ok {
+		return opt, nil
+	}
+	var found Option
+	hits := 0
+	for full, opt := range options {
+		if strings.HasPrefix(full, name) {
+			found = opt
+			hits++
+		}
+	}
+	switch {
+	case hits > 1:
+		return Option{}, ParamOptionAmbiguous
+	case hits == 0 || name == "":
+		return Option{}, ParamOptionUnknown
+	}
+	return found, nil
+}
+
 // --- Option Handlers ---
 
 func handleString(fieldName string) func(*ParameterParser, *OperationConfig, string) error {
@@ -172,6 +393,14 @@ func handleString(fieldName string) func(*ParameterParser, *OperationConfig, str
 			config.CustomRequest = arg
 		case "UserPassword":
 			config.UserPassword = arg
+		case "Referer":
+			config.Referer = arg
+		case "Proxy":
+			config.Proxy = arg
+		case "ProxyUserPassword":
+			config.ProxyUserPassword = arg
+		case "HeaderFile":
+			config.HeaderFile = arg
 		}
 		return nil
 	}
@@ -181,13 +410,37 @@ func handleBool(fieldName string) func(*ParameterParser, *OperationConfig, strin
 	return func(p *ParameterParser, config *OperationConfig, arg string) error {
 		switch fieldName {
 		case "InsecureOK":
-			config.InsecureOK = true
+			config.InsecureOK = p.toggle
 		case "FollowLocation":
-			config.FollowLocation = true
+			config.FollowLocation = p.toggle
 		case "UseHTTPGet":
-			config.UseHTTPGet = true
+			config.UseHTTPGet = p.toggle
 		case "FailOnError":
-			config.FailOnError = true
+			config.FailOnError = p.toggle
+		case "ShowHeaders":
+			config.ShowHeaders = p.toggle
+		case "ContentDisposition":
+			config.ContentDisposition = p.toggle
+		case "RemoteTime":
+			config.RemoteTime = p.toggle
+		case "FormEscape":
+			config.FormEscape = p.toggle
+		case "SparseRanges":
+			config.SparseRanges = p.toggle
+		}
+		return nil
+	}
+}
+
+func handleGlobalBool(fieldName string) func(*ParameterParser, *OperationConfig, string) error {
+	return func(p *ParameterParser, config *OperationConfig, arg string) error {
+		switch fieldName {
+		case "Parallel":
+			p.Global.Parallel = p.toggle
+		case "ParallelConnect":
+			p.Global.ParallelConnect = p.toggle
+		case "FailEarly":
+			p.Global.FailEarly = p.toggle
 		}
 		return nil
 	}
@@ -195,77 +448,436 @@ func handleBool(fieldName string) func(*ParameterParser, *OperationConfig, strin
 
 func handleAuth(authType AuthType) func(*ParameterParser, *OperationConfig, string) error {
 	return func(p *ParameterParser, config *OperationConfig, arg string) error {
-		if authType == AuthAny {
-			config.AuthType = uint(AuthAny)
-		} else {
+		switch {
+		case authType == AuthAny:
+			// --no-anyauth leaves the selection alone, as in C.
+			if p.toggle {
+				config.AuthType = uint(AuthAny)
+			}
+		case p.toggle:
 			config.AuthType |= uint(authType)
+		default:
+			config.AuthType &^= uint(authType)
 		}
 		return nil
 	}
 }
 
 func handleVerbose(p *ParameterParser, config *OperationConfig, arg string) error {
+	p.Global.Verbose = p.toggle
+	return nil
+}
+
+func handleSilent(p *ParameterParser, config *OperationConfig, arg string) error {
+	p.Global.Silent = p.toggle
+	return nil
+}
+
+func handleShowError(p *ParameterParser, config *OperationConfig, arg string) error {
+	p.Global.ShowError = p.toggle
 	return nil
 }
 
 func handleHead(p *ParameterParser, config *OperationConfig, arg string) error {
-	config.NoBody = true
-	config.ShowHeaders = true
-	config.UseHTTPGet = false
+	if p.toggle {
+		if err := setHTTPRequest(config, HTTPRequestHead); err != nil {
+			return err
+		}
+	}
+	config.NoBody = p.toggle
+	config.ShowHeaders = p.toggle
+	if p.toggle {
+		config.UseHTTPGet = false
+	}
 	return nil
 }
 
-func handleURL(p *ParameterParser, config *OperationConfig, arg string) error {
-	urlConf := &URLConfig{URL: arg, IsSet: true}
+// nextURLNode returns the first node in the URL list for which used reports
+// false, appending a new node if every existing one is taken. This mirrors
+// how the C code pairs URLs with -o/-O by looking for the first `getout`
+// node that lacks the corresponding flag, so "-o file URL" and "URL -o file"
+// both attach the output name to the same URL.
+func nextURLNode(config *OperationConfig, used func(*URLConfig) bool) *URLConfig {
+	for _, u := range config.URLList {
+		if !used(u) {
+			return u
+		}
+	}
+	urlConf := &URLConfig{IsSet: true, NoGlob: config.GlobOff}
 	config.URLList = append(config.URLList, urlConf)
+	return urlConf
+}
+
+func hasURL(u *URLConfig) bool     { return u.URL != "" }
+func hasOutfile(u *URLConfig) bool { return u.Outfile != "" || u.UseRemote }
+func hasInfile(u *URLConfig) bool  { return u.Infile != "" }
+
+// handleGlobOff implements -g/--globoff. As in curl, it applies to all the
+// URLs of the operation, including those given before it.
+func handleGlobOff(p *ParameterParser, config *OperationConfig, arg string) error {
+	config.GlobOff = p.toggle
+	for _, u := range config.URLList {
+		u.NoGlob = p.toggle
+	}
+	return nil
+}
+
+func handleURL(p *ParameterParser, config *OperationConfig, arg string) error {
+	nextURLNode(config, hasURL).URL = arg
+	return nil
+}
+
+// handleNext implements --next. It appends a new OperationConfig to the
+// chain so that the options and URLs that follow form an operation of their
+// own. Like the C code in `parse_args`, it refuses to do so before the
+// current operation has a URL.
+func handleNext(p *ParameterParser, config *OperationConfig, arg string) error {
+	if len(config.URLList) == 0 || config.URLList[0].URL == "" {
+		return paramErrorf(ParamBadUse, "missing URL before --next")
+	}
+	next := NewOperationConfig()
+	next.Prev = config
+	config.Next = next
+	p.Global.Last = next
 	return nil
 }
 
+// handleConfig reads the options of another config file, like curl's
+// -K/--config. The file name "-" reads from stdin.
+func handleConfig(p *ParameterParser, config *OperationConfig, arg string) error {
+	return p.ParseConfigFile(arg)
+}
+
+// handleDisable implements -q/--disable. It only has an effect as the first
+// argument, where Operate checks for it before loading the default config
+// file; anywhere else it is accepted and ignored, as in curl.
+func handleDisable(p *ParameterParser, config *OperationConfig, arg string) error {
+	return nil
+}
+
+// handleVariable sets a variable with --variable.
+func handleVariable(p *ParameterParser, config *OperationConfig, arg string) error {
+	return setVariable(p.Global, arg)
+}
+
 func handleHeader(p *ParameterParser, config *OperationConfig, arg string) error {
 	config.Headers = append(config.Headers, arg)
 	return nil
 }
 
-func handleData(p *ParameterParser, config *OperationConfig, arg string) error {
-	config.PostFields = arg
+// The kinds of data options handled by handleData.
+const (
+	dataASCII     = iota // -d, --data, --data-ascii
+	dataBinary           // --data-binary
+	dataRaw              // --data-raw
+	dataURLEncode        // --data-urlencode
+	dataJSON             // --json
+)
+
+// handleData implements the -d family of options. Each use adds a piece of
+// data to the request body, the pieces being joined with '&', and makes the
+// request a POST. An argument starting with '@' names a file to read the
+// data from, "@-" being stdin: -d strips carriage returns and newlines from
+// it while --data-binary keeps it verbatim. --data-raw takes '@' literally
+// and --data-urlencode encodes its argument as described for
+// urlEncodeData. --json works like --data-binary but adds no '&' before its
+// data, so that repeated uses build up one JSON document, and makes the
+// request send JSON content-type and accept headers.
+func handleData(kind int) func(*ParameterParser, *OperationConfig, string) error {
+	return func(p *ParameterParser, config *OperationConfig, arg string) error {
+		data := arg
+		var err error
+		switch {
+		case kind == dataURLEncode:
+			data, err = urlEncodeData(arg)
+		case kind != dataRaw && strings.HasPrefix(arg, "@"):
+			data, err = readData(arg[1:], kind == dataASCII)
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := setHTTPRequest(config, HTTPRequestSimplePost); err != nil {
+			return err
+		}
+		if config.PostFields != "" && kind != dataJSON {
+			config.PostFields += "&"
+		}
+		config.PostFields += data
+		if kind == dataJSON {
+			config.JSON = true
+		}
+		return nil
+	}
+}
+
+// handleForm adds a part to the multipart form of the request, like curl's
+// -F/--form and, with literal set, --form-string, whose value is used as
+// it is. Several files given to one -F, as in "name=@a,b", are sent
+// together in a multipart/mixed part. "-F name=(" opens a multipart that
+// receives the parts that follow, until "-F =)" closes it.
+func handleForm(literal bool) func(*ParameterParser, *OperationConfig, string) error {
+	return func(p *ParameterParser, config *OperationConfig, arg string) error {
+		if !literal && arg == "=)" {
+			if len(config.FormNesting) == 0 {
+				return paramErrorf(ParamBadUse, "no multipart to terminate")
+			}
+			config.FormNesting = config.FormNesting[:len(config.FormNesting)-1]
+			return nil
+		}
+
+		var part *FormPart
+		if literal {
+			name, value, found := strings.Cut(arg, "=")
+			if !found {
+				return paramErrorf(ParamBadUse, "Illegally formatted input field")
+			}
+			part = &FormPart{Name: name, Value: value, Type: PartTypeLiteral}
+		} else {
+			parts, err := ParseFormString(arg)
+			if err != nil {
+				return paramErrorf(ParamBadUse, "%v", err)
+			}
+			part = parts[0]
+			if len(parts) > 1 {
+				part = &FormPart{Name: parts[0].Name, Type: PartTypeMultipart, Parts: parts}
+				for _, file := range parts {
+					file.Name = ""
+				}
+			}
+		}
+
+		if err := setHTTPRequest(config, HTTPRequestMimePost); err != nil {
+			return err
+		}
+		if n := len(config.FormNesting); n > 0 {
+			current := config.FormNesting[n-1]
+			current.Parts = append(current.Parts, part)
+		} else {
+			config.Form = append(config.Form, part)
+		}
+		if part.Type == PartTypeMultipart && len(part.Parts) == 0 {
+			config.FormNesting = append(config.FormNesting, part)
+		}
+		return nil
+	}
+}
+
+// handleURLQuery adds a part to the query of every URL, like curl's
+// --url-query. The argument takes the --data-urlencode forms, and one
+// starting with '+' is used verbatim.
+func handleURLQuery(p *ParameterParser, config *OperationConfig, arg string) error {
+	query, verbatim := strings.CutPrefix(arg, "+")
+	if !verbatim {
+		var err error
+		if query, err = urlEncodeData(arg); err != nil {
+			return err
+		}
+	}
+	if config.Query != "" {
+		config.Query += "&"
+	}
+	config.Query += query
+	return nil
+}
+
+// urlEncodeData returns the data for a --data-urlencode argument, which is
+// one of:
+//
+//	content        content is URL encoded
+//	=content       content is URL encoded, without the '='
+//	name=content   content is URL encoded and appended to "name="
+//	@file          the content of file is URL encoded
+//	name@file      the content of file is URL encoded and appended to "name="
+//
+// An '=' takes precedence over an '@', as in the C code.
+func urlEncodeData(arg string) (string, error) {
+	i := strings.IndexByte(arg, '=')
+	if i < 0 {
+		i = strings.IndexByte(arg, '@')
+	}
+	if i < 0 {
+		return urlEscape(arg), nil
+	}
+
+	name, content := arg[:i], arg[i+1:]
+	if arg[i] == '@' {
+		var err error
+		if content, err = readData(content, false); err != nil {
+			return "", err
+		}
+	}
+	if name == "" {
+		return urlEscape(content), nil
+	}
+	return name + "=" + urlEscape(content), nil
+}
+
+// readData reads the data of a data option from a file, "-" being stdin.
+// With strip set, carriage returns and newlines are removed. As in curl, a
+// file that cannot be read fails the option with ParamReadError.
+func readData(name string, strip bool) (string, error) {
+	file := os.Stdin
+	if name != "-" {
+		var err error
+		if file, err = os.Open(name); err != nil {
+			return "", paramErrorf(ParamReadError, "Failed to open %s", name)
+		}
+		defer file.Close()
+	}
+
+	if strip {
+		data, err := FileToString(file)
+		if err != nil {
+			return "", paramErrorf(ParamReadError, "Failed to read %s", name)
+		}
+		return data, nil
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", paramErrorf(ParamReadError, "Failed to read %s", name)
+	}
+	return string(data), nil
+}
+
+// setHTTPRequest sets the request method implied by an option, failing
+// when another option already implied a different one. It corresponds to
+// the C function `SetHTTPrequest`.
+func setHTTPRequest(config *OperationConfig, req HTTPRequest) error {
+	if err := config.HTTPReq.Set(req); err != nil {
+		return paramErrorf(ParamBadUse, "%v", err)
+	}
 	return nil
 }
 
 func handleOutputFile(p *ParameterParser, config *OperationConfig, arg string) error {
-	if len(config.URLList) > 0 {
-		config.URLList[len(config.URLList)-1].Outfile = arg
-	} else {
-		urlConf := &URLConfig{Outfile: arg, IsSet: true}
-		config.URLList = append(config.URLList, urlConf)
+	nextURLNode(config, hasOutfile).Outfile = arg
+	return nil
+}
+
+// handleUploadFile implements -T/--upload-file. The file is paired with a
+// URL the way -o is, and makes the request a PUT.
+func handleUploadFile(p *ParameterParser, config *OperationConfig, arg string) error {
+	if err := setHTTPRequest(config, HTTPRequestPut); err != nil {
+		return err
 	}
+	nextURLNode(config, hasInfile).Infile = arg
 	return nil
 }
 
 func handleRemoteName(p *ParameterParser, config *OperationConfig, arg string) error {
-	if len(config.URLList) > 0 {
-		config.URLList[len(config.URLList)-1].UseRemote = true
+	if !p.toggle {
+		// --no-remote-name only undoes a default, of which there is none.
+		return nil
+	}
+	nextURLNode(config, hasOutfile).UseRemote = true
+	return nil
+}
+
+func handleMaxRedirs(p *ParameterParser, config *OperationConfig, arg string) error {
+	val, err := ParseLong(arg)
+	if err != nil || val < -1 {
+		return ParamBadNumeric
+	}
+	config.MaxRedirs = val
+	return nil
+}
+
+// handleWriteOut stores the --write-out format. Like curl, an argument
+// starting with '@' names a file to read the format from, "@-" being stdin.
+func handleWriteOut(p *ParameterParser, config *OperationConfig, arg string) error {
+	if !strings.HasPrefix(arg, "@") {
+		config.WriteOut = arg
+		return nil
+	}
+	var content []byte
+	var err error
+	if name := arg[1:]; name == "-" {
+		content, err = io.ReadAll(os.Stdin)
 	} else {
-		urlConf := &URLConfig{UseRemote: true, IsSet: true}
-		config.URLList = append(config.URLList, urlConf)
+		content, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return paramErrorf(ParamReadError, "%v", err)
 	}
+	config.WriteOut = string(content)
 	return nil
 }
 
 func handleConnectTimeout(p *ParameterParser, config *OperationConfig, arg string) error {
 	val, err := ParseSecs(arg)
 	if err != nil {
-		return err
+		return numericError(arg)
 	}
 	config.ConnectTimeout = val
 	return nil
 }
 
+// handleParallelMax sets --parallel-max. As in C, values above
+// parallelMaxLimit are capped and values below 1 select the default.
+func handleParallelMax(p *ParameterParser, config *OperationConfig, arg string) error {
+	val, err := ParseULong(arg)
+	if err != nil {
+		return numericError(arg)
+	}
+	switch {
+	case val > parallelMaxLimit:
+		p.Global.ParallelMax = parallelMaxLimit
+	case val < 1:
+		p.Global.ParallelMax = parallelDefault
+	default:
+		p.Global.ParallelMax = int(val)
+	}
+	return nil
+}
+
+// handleSegments sets --segments. Like --parallel-max, values above
+// parallelMaxLimit are capped.
+func handleSegments(p *ParameterParser, config *OperationConfig, arg string) error {
+	val, err := ParseULong(arg)
+	if err != nil || val < 1 {
+		return numericError(arg)
+	}
+	config.Segments = int64(min(val, parallelMaxLimit))
+	return nil
+}
+
+func handleMaxTime(p *ParameterParser, config *OperationConfig, arg string) error {
+	val, err := ParseSecs(arg)
+	if err != nil {
+		return numericError(arg)
+	}
+	config.Timeout = val
+	return nil
+}
+
+// handleContinueAt implements -C/--continue-at: resume the transfer at the
+// given offset or, with "-", at the size of the output file.
+func handleContinueAt(p *ParameterParser, config *OperationConfig, arg string) error {
+	if config.Range != "" {
+		return paramErrorf(ParamBadUse, "--continue-at is mutually exclusive with --range")
+	}
+	if arg == "-" {
+		config.ResumeFromCurrent = true
+		config.ResumeFrom = 0
+	} else {
+		val, err := ParseLong(arg)
+		if err != nil || val < 0 {
+			return numericError(arg)
+		}
+		config.ResumeFromCurrent = false
+		config.ResumeFrom = val
+	}
+	config.UseResume = true
+	return nil
+}
+
 func handleRange(p *ParameterParser, config *OperationConfig, arg string) error {
 	if config.UseResume {
-		return fmt.Errorf("--continue-at is mutually exclusive with --range")
+		return paramErrorf(ParamBadUse, "--continue-at is mutually exclusive with --range")
 	}
 	if !strings.Contains(arg, "-") {
-		return fmt.Errorf("a range must contain at least one dash")
+		return paramErrorf(ParamBadUse, "A specified range MUST include at least one dash (-).")
 	}
 	config.Range = arg
 	return nil
@@ -343,4 +955,4 @@ func parseCertParameter(param string) (certname, passphrase string) {
 		}
 	}
 	return sb.String(), ""
-}
\ No newline at end of file
+}
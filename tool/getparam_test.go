@@ -1,7 +1,11 @@
 package tool
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"testing"
 	"time"
 )
@@ -159,6 +163,618 @@ func TestParameterParser_Parse(t *testing.T) {
 	})
 }
 
+func TestParameterParser_URLOutputPairing(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected []URLConfig
+	}{
+		{
+			name:     "output before URL",
+			args:     []string{"-o", "a.txt", "http://a"},
+			expected: []URLConfig{{URL: "http://a", Outfile: "a.txt", IsSet: true}},
+		},
+		{
+			name:     "output after URL",
+			args:     []string{"http://a", "-o", "a.txt"},
+			expected: []URLConfig{{URL: "http://a", Outfile: "a.txt", IsSet: true}},
+		},
+		{
+			name: "outputs in order",
+			args: []string{"-o", "a.txt", "-O", "http://a", "http://b", "http://c"},
+			expected: []URLConfig{
+				{URL: "http://a", Outfile: "a.txt", IsSet: true},
+				{URL: "http://b", UseRemote: true, IsSet: true},
+				{URL: "http://c", IsSet: true},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			global := NewGlobalConfig()
+			parser := NewParameterParser(global)
+			if err := parser.Parse(tc.args); err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			list := global.Last.URLList
+			if len(list) != len(tc.expected) {
+				t.Fatalf("URLList has %d entries; want %d", len(list), len(tc.expected))
+			}
+			for i, want := range tc.expected {
+				if *list[i] != want {
+					t.Errorf("URLList[%d] = %+v; want %+v", i, *list[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestParameterParser_OutputOptions(t *testing.T) {
+	args := []string{
+		"-s", "-S", "-i", "-J", "-R",
+		"-D", "headers.txt",
+		"-w", "%{http_code}",
+		"-e", "http://ref",
+		"-x", "proxy:8080",
+		"-U", "pu:pp",
+		"--max-redirs", "3",
+	}
+	global := NewGlobalConfig()
+	parser := NewParameterParser(global)
+	if err := parser.Parse(args); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	config := global.Last
+	if !global.Silent || !global.ShowError {
+		t.Error("--silent and --show-error should be set on the global config")
+	}
+	if !config.ShowHeaders || !config.ContentDisposition || !config.RemoteTime {
+		t.Error("-i, -J and -R should all be set")
+	}
+	if config.HeaderFile != "headers.txt" || config.WriteOut != "%{http_code}" {
+		t.Errorf("HeaderFile = %q, WriteOut = %q", config.HeaderFile, config.WriteOut)
+	}
+	if config.Referer != "http://ref" || config.Proxy != "proxy:8080" || config.ProxyUserPassword != "pu:pp" {
+		t.Errorf("Referer = %q, Proxy = %q, ProxyUserPassword = %q", config.Referer, config.Proxy, config.ProxyUserPassword)
+	}
+	if config.MaxRedirs != 3 {
+		t.Errorf("MaxRedirs = %d; want 3", config.MaxRedirs)
+	}
+}
+
+func TestParameterParser_Errors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		args       []string
+		wantCode   ParameterError
+		wantOption string
+		wantArg    string
+		wantText   string
+	}{
+		{
+			name:       "unknown long option",
+			args:       []string{"--foo"},
+			wantCode:   ParamOptionUnknown,
+			wantOption: "--foo",
+			wantText:   "option --foo: is unknown",
+		},
+		{
+			name:       "unknown short option in bundle",
+			args:       []string{"-sQ"},
+			wantCode:   ParamOptionUnknown,
+			wantOption: "-sQ",
+			wantText:   "option -sQ: is unknown",
+		},
+		{
+			name:       "missing argument",
+			args:       []string{"http://a", "-o"},
+			wantCode:   ParamRequiresParameter,
+			wantOption: "-o",
+			wantText:   "option -o: requires parameter",
+		},
+		{
+			name:       "bad number",
+			args:       []string{"--max-redirs", "many"},
+			wantCode:   ParamBadNumeric,
+			wantOption: "--max-redirs",
+			wantArg:    "many",
+			wantText:   "option --max-redirs: expected a proper numerical parameter",
+		},
+		{
+			name:       "negative number",
+			args:       []string{"--max-time", "-1"},
+			wantCode:   ParamNegativeNumeric,
+			wantOption: "--max-time",
+			wantArg:    "-1",
+			wantText:   "option --max-time: expected a positive numerical parameter",
+		},
+		{
+			name:       "bad range",
+			args:       []string{"-r", "1024"},
+			wantCode:   ParamBadUse,
+			wantOption: "-r",
+			wantArg:    "1024",
+			wantText:   "option -r: is badly used here",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := NewParameterParser(NewGlobalConfig()).Parse(tc.args)
+			var oe *OptionError
+			if !errors.As(err, &oe) {
+				t.Fatalf("Parse() error = %v (%T); want *OptionError", err, err)
+			}
+			if oe.Code != tc.wantCode || oe.Option != tc.wantOption || oe.Arg != tc.wantArg {
+				t.Errorf("OptionError = %+v; want code %d, option %q, arg %q",
+					*oe, tc.wantCode, tc.wantOption, tc.wantArg)
+			}
+			if err.Error() != tc.wantText {
+				t.Errorf("Error() = %q; want %q", err.Error(), tc.wantText)
+			}
+			if !errors.Is(err, tc.wantCode) {
+				t.Errorf("errors.Is(err, %v) = false", tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestParameterParser_Next(t *testing.T) {
+	args := []string{
+		"-H", "X-One: 1", "http://a", "-o", "a.txt",
+		"-:",
+		"-d", "x=1", "-X", "PUT", "http://b",
+		"--next",
+		"http://c",
+	}
+	global := NewGlobalConfig()
+	parser := NewParameterParser(global)
+	if err := parser.Parse(args); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	var configs []*OperationConfig
+	for config := global.First; config != nil; config = config.Next {
+		configs = append(configs, config)
+	}
+	if len(configs) != 3 || global.Last != configs[2] {
+		t.Fatalf("chain has %d operations; want 3 ending at global.Last", len(configs))
+	}
+	if configs[1].Prev != configs[0] || configs[2].Prev != configs[1] {
+		t.Error("Prev pointers do not link the chain back")
+	}
+
+	first, second, third := configs[0], configs[1], configs[2]
+	if len(first.Headers) != 1 || first.URLList[0].Outfile != "a.txt" || first.PostFields != "" {
+		t.Errorf("first operation = %+v", first)
+	}
+	if second.PostFields != "x=1" || second.CustomRequest != "PUT" || len(second.Headers) != 0 {
+		t.Errorf("second operation = %+v", second)
+	}
+	if third.URLList[0].URL != "http://c" || third.CustomRequest != "" {
+		t.Errorf("third operation = %+v", third)
+	}
+
+	for _, args := range [][]string{{"--next", "http://a"}, {"http://a", "-:", "-:"}} {
+		global := NewGlobalConfig()
+		err := NewParameterParser(global).Parse(args)
+		var oe *OptionError
+		if !errors.As(err, &oe) || oe.Code != ParamBadUse || oe.Detail != "missing URL before --next" {
+			t.Errorf("Parse(%q) error = %v; want missing URL", args, err)
+		}
+	}
+}
+
+func TestParameterParser_Data(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "data.txt")
+	if err := os.WriteFile(file, []byte("line1\r\nline2 & more\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"single", []string{"-d", "a=b"}, "a=b"},
+		{"joined with ampersand", []string{"-d", "a=b", "--data-ascii", "c=d", "--data-binary", "e"}, "a=b&c=d&e"},
+		{"file stripped", []string{"-d", "@" + file}, "line1line2 & more"},
+		{"file verbatim", []string{"--data-binary", "@" + file}, "line1\r\nline2 & more\n"},
+		{"raw keeps at sign", []string{"--data-raw", "@" + file}, "@" + file},
+		{"urlencode content", []string{"--data-urlencode", "a b&c"}, "a%20b%26c"},
+		{"urlencode leading equals", []string{"--data-urlencode", "=x=y"}, "x%3Dy"},
+		{"urlencode name", []string{"--data-urlencode", "q=hello world"}, "q=hello%20world"},
+		{"urlencode file", []string{"--data-urlencode", "@" + file}, "line1%0D%0Aline2%20%26%20more%0A"},
+		{"urlencode name and file", []string{"--data-urlencode", "f@" + file}, "f=line1%0D%0Aline2%20%26%20more%0A"},
+		{"equals before at", []string{"--data-urlencode", "a=b@c"}, "a=b%40c"},
+		{"json not separated", []string{"--json", `{"a":`, "--json", "1}"}, `{"a":1}`},
+		{"json file verbatim", []string{"--json", "@" + file}, "line1\r\nline2 & more\n"},
+		{"data after json", []string{"--json", "{}", "-d", "x"}, "{}&x"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			global := NewGlobalConfig()
+			if err := NewParameterParser(global).Parse(tc.args); err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			config := global.Last
+			if config.PostFields != tc.want {
+				t.Errorf("PostFields = %q; want %q", config.PostFields, tc.want)
+			}
+			if config.HTTPReq.Get() != HTTPRequestSimplePost {
+				t.Errorf("HTTPReq = %v; want %v", config.HTTPReq.Get(), HTTPRequestSimplePost)
+			}
+		})
+	}
+
+	t.Run("stdin", func(t *testing.T) {
+		stdin, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stdin.Close()
+		saved := os.Stdin
+		os.Stdin = stdin
+		defer func() { os.Stdin = saved }()
+
+		global := NewGlobalConfig()
+		if err := NewParameterParser(global).Parse([]string{"-d", "@-"}); err != nil {
+			t.Fatalf("Parse() failed: %v", err)
+		}
+		if global.Last.PostFields != "line1line2 & more" {
+			t.Errorf("PostFields = %q", global.Last.PostFields)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		missing := filepath.Join(dir, "nope")
+		for _, args := range [][]string{
+			{"-d", "@" + missing},
+			{"--data-binary", "@" + missing},
+			{"--data-urlencode", "name@" + missing},
+		} {
+			global := NewGlobalConfig()
+			err := NewParameterParser(global).Parse(args)
+			var oe *OptionError
+			if !errors.As(err, &oe) || oe.Code != ParamReadError || oe.Detail != "Failed to open "+missing {
+				t.Errorf("Parse(%q) error = %v; want a read error", args, err)
+			}
+			if code := ErrorCode(err); code != CurlReadError {
+				t.Errorf("ErrorCode(Parse(%q)) = %d; want %d", args, code, CurlReadError)
+			}
+			if global.Last.PostFields != "" {
+				t.Errorf("PostFields = %q; want none", global.Last.PostFields)
+			}
+		}
+	})
+
+	t.Run("conflicts with head", func(t *testing.T) {
+		err := NewParameterParser(NewGlobalConfig()).Parse([]string{"-I", "-d", "x"})
+		var oe *OptionError
+		if !errors.As(err, &oe) || oe.Code != ParamBadUse || oe.Option != "-d" {
+			t.Fatalf("Parse() error = %v; want -d badly used", err)
+		}
+		if !strings.Contains(oe.Detail, "POST (-d, --data) and HEAD (-I, --head)") {
+			t.Errorf("Detail = %q", oe.Detail)
+		}
+	})
+}
+
+func TestParameterParser_Form(t *testing.T) {
+	global := NewGlobalConfig()
+	args := []string{"-F", "name=value", "--form-string", "raw=@literal;type=x", "-F", "files=@a.txt,b.txt"}
+	if err := NewParameterParser(global).Parse(args); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	config := global.Last
+	if config.HTTPReq.Get() != HTTPRequestMimePost {
+		t.Errorf("HTTPReq = %v; want %v", config.HTTPReq.Get(), HTTPRequestMimePost)
+	}
+	if len(config.Form) != 3 {
+		t.Fatalf("Form has %d parts; want 3", len(config.Form))
+	}
+	if part := config.Form[1]; part.Type != PartTypeLiteral || part.Value != "@literal;type=x" {
+		t.Errorf("--form-string part = %+v", part)
+	}
+	if part := config.Form[2]; part.Name != "files" || len(part.Parts) != 2 {
+		t.Errorf("multiple files part = %+v", part)
+	}
+
+	for _, args := range [][]string{
+		{"-d", "x", "-F", "a=b"},
+		{"-F", "a=b", "-I"},
+		{"--form-string", "novalue"},
+		{"-F", "=)"},
+		{"-F", "a=(", "-F", "=)", "-F", "=)"},
+	} {
+		var oe *OptionError
+		err := NewParameterParser(NewGlobalConfig()).Parse(args)
+		if !errors.As(err, &oe) || oe.Code != ParamBadUse {
+			t.Errorf("Parse(%q) error = %v; want %v", args, err, ParamBadUse)
+		}
+	}
+}
+
+func TestParameterParser_UploadFile(t *testing.T) {
+	global := NewGlobalConfig()
+	args := []string{"-T", "a.txt", "http://one/", "http://two/", "--upload-file", "b.txt"}
+	if err := NewParameterParser(global).Parse(args); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	config := global.Last
+	if config.HTTPReq.Get() != HTTPRequestPut {
+		t.Errorf("HTTPReq = %v; want %v", config.HTTPReq.Get(), HTTPRequestPut)
+	}
+	if len(config.URLList) != 2 || config.URLList[0].Infile != "a.txt" || config.URLList[1].Infile != "b.txt" {
+		t.Errorf("URLList = %+v", config.URLList)
+	}
+
+	for _, args := range [][]string{
+		{"-d", "x", "-T", "file"},
+		{"-T", "file", "-F", "a=b"},
+		{"-T", "file", "-I"},
+	} {
+		var oe *OptionError
+		err := NewParameterParser(NewGlobalConfig()).Parse(args)
+		if !errors.As(err, &oe) || oe.Code != ParamBadUse {
+			t.Errorf("Parse(%q) error = %v; want %v", args, err, ParamBadUse)
+		}
+	}
+}
+
+func TestParameterParser_ContinueAt(t *testing.T) {
+	testCases := []struct {
+		args    []string
+		from    int64
+		current bool
+	}{
+		{[]string{"-C", "1024"}, 1024, false},
+		{[]string{"--continue-at", "-"}, 0, true},
+		{[]string{"-C", "-", "-C", "7"}, 7, false},
+		{[]string{"-J", "-C", "10"}, 10, false},
+	}
+	for _, tc := range testCases {
+		global := NewGlobalConfig()
+		if err := NewParameterParser(global).Parse(tc.args); err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tc.args, err)
+		}
+		config := global.Last
+		if !config.UseResume || config.ResumeFrom != tc.from || config.ResumeFromCurrent != tc.current {
+			t.Errorf("Parse(%q): UseResume = %v, ResumeFrom = %d, ResumeFromCurrent = %v",
+				tc.args, config.UseResume, config.ResumeFrom, config.ResumeFromCurrent)
+		}
+	}
+
+	errorCases := []struct {
+		args []string
+		want ParameterError
+	}{
+		{[]string{"-C", "x"}, ParamBadNumeric},
+		{[]string{"-C", "-5"}, ParamNegativeNumeric},
+		{[]string{"-r", "0-9", "-C", "5"}, ParamBadUse},
+		{[]string{"-C", "5", "-r", "0-9"}, ParamBadUse},
+		{[]string{"-J", "-C", "-"}, ParamContDispResumeFrom},
+		{[]string{"-C", "-", "-OJ"}, ParamContDispResumeFrom},
+	}
+	for _, tc := range errorCases {
+		err := NewParameterParser(NewGlobalConfig()).Parse(tc.args)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("Parse(%q) error = %v; want %v", tc.args, err, tc.want)
+		}
+	}
+}
+
+func TestParameterParser_Parallel(t *testing.T) {
+	testCases := []struct {
+		name    string
+		args    []string
+		wantMax int
+	}{
+		{"default", []string{"-Z"}, 50},
+		{"explicit", []string{"--parallel", "--parallel-max", "7"}, 7},
+		{"capped", []string{"--parallel-max", "1000"}, 300},
+		{"zero selects default", []string{"--parallel-max", "0"}, 50},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			global := NewGlobalConfig()
+			parser := NewParameterParser(global)
+			if err := parser.Parse(tc.args); err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			if global.ParallelMax != tc.wantMax {
+				t.Errorf("ParallelMax = %d; want %d", global.ParallelMax, tc.wantMax)
+			}
+		})
+	}
+
+	global := NewGlobalConfig()
+	parser := NewParameterParser(global)
+	if err := parser.Parse([]string{"--parallel-immediate", "--fail-early", "-Z"}); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if !global.Parallel || !global.ParallelConnect || !global.FailEarly {
+		t.Errorf("Parallel = %v, ParallelConnect = %v, FailEarly = %v; want all true",
+			global.Parallel, global.ParallelConnect, global.FailEarly)
+	}
+	if err := parser.Parse([]string{"--parallel-max", "-3"}); err == nil {
+		t.Error("a negative --parallel-max should be rejected")
+	}
+}
+
+func TestParameterParser_GlobOff(t *testing.T) {
+	global := NewGlobalConfig()
+	args := []string{"http://a/[1-2]", "-g", "-o", "out", "http://b/{x,y}", "--next", "http://c/[1-2]"}
+	if err := NewParameterParser(global).Parse(args); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	// -g covers every URL of its operation, but not the next one.
+	for _, u := range global.First.URLList {
+		if !u.NoGlob {
+			t.Errorf("NoGlob of %q = false; want true", u.URL)
+		}
+	}
+	if u := global.Last.URLList[0]; u.NoGlob {
+		t.Errorf("NoGlob of %q = true after --next", u.URL)
+	}
+}
+
+func TestParameterParser_Segments(t *testing.T) {
+	testCases := []struct {
+		arg  string
+		want int64
+	}{
+		{"4", 4},
+		{"1", 1},
+		{"1000", 300},
+	}
+	for _, tc := range testCases {
+		global := NewGlobalConfig()
+		if err := NewParameterParser(global).Parse([]string{"--segments", tc.arg}); err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tc.arg, err)
+		}
+		if global.Last.Segments != tc.want {
+			t.Errorf("--segments %s: Segments = %d; want %d", tc.arg, global.Last.Segments, tc.want)
+		}
+	}
+
+	for _, arg := range []string{"0", "-2", "many"} {
+		if err := NewParameterParser(NewGlobalConfig()).Parse([]string{"--segments", arg}); err == nil {
+			t.Errorf("--segments %s should be rejected", arg)
+		}
+	}
+}
+
+func TestParameterParser_ShortOptionBundling(t *testing.T) {
+	t.Run("booleans", func(t *testing.T) {
+		global := NewGlobalConfig()
+		if err := NewParameterParser(global).Parse([]string{"-sSfL", "http://a"}); err != nil {
+			t.Fatalf("Parse() failed: %v", err)
+		}
+		config := global.Last
+		if !global.Silent || !global.ShowError || !config.FailOnError || !config.FollowLocation {
+			t.Errorf("Silent = %v, ShowError = %v, FailOnError = %v, FollowLocation = %v; want all true",
+				global.Silent, global.ShowError, config.FailOnError, config.FollowLocation)
+		}
+		if len(config.URLList) != 1 || config.URLList[0].URL != "http://a" {
+			t.Errorf("URLList = %+v", config.URLList)
+		}
+	})
+
+	t.Run("argument from next element", func(t *testing.T) {
+		global := NewGlobalConfig()
+		if err := NewParameterParser(global).Parse([]string{"-vko", "out.txt", "http://a"}); err != nil {
+			t.Fatalf("Parse() failed: %v", err)
+		}
+		config := global.Last
+		if !global.Verbose || !config.InsecureOK {
+			t.Error("-v and -k should both be set")
+		}
+		if len(config.URLList) != 1 || config.URLList[0].Outfile != "out.txt" || config.URLList[0].URL != "http://a" {
+			t.Errorf("URLList = %+v", config.URLList)
+		}
+	})
+
+	t.Run("argument from remainder", func(t *testing.T) {
+		global := NewGlobalConfig()
+		parser := NewParameterParser(global)
+		used, err := parser.ParseOne("-kLAagent/1", "http://a")
+		if err != nil {
+			t.Fatalf("ParseOne() failed: %v", err)
+		}
+		if used {
+			t.Error("ParseOne() should not use the next argument")
+		}
+		config := global.Last
+		if !config.InsecureOK || !config.FollowLocation || config.UserAgent != "agent/1" {
+			t.Errorf("InsecureOK = %v, FollowLocation = %v, UserAgent = %q",
+				config.InsecureOK, config.FollowLocation, config.UserAgent)
+		}
+	})
+
+	errorCases := []struct {
+		name string
+		flag string
+	}{
+		{"unknown letter", "-sQ"},
+		{"missing argument", "-so"},
+		{"lone dash", "-"},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser := NewParameterParser(NewGlobalConfig())
+			if _, err := parser.ParseOne(tc.flag, ""); err == nil {
+				t.Errorf("ParseOne(%q) should fail", tc.flag)
+			}
+		})
+	}
+}
+
+func TestParameterParser_LongOptionNames(t *testing.T) {
+	t.Run("no- prefix negates booleans", func(t *testing.T) {
+		global := NewGlobalConfig()
+		parser := NewParameterParser(global)
+		args := []string{"-L", "-k", "-v", "-i", "--no-location", "--no-insecure", "--no-verbose", "--no-include"}
+		if err := parser.Parse(args); err != nil {
+			t.Fatalf("Parse() failed: %v", err)
+		}
+		config := global.Last
+		if config.FollowLocation || config.InsecureOK || global.Verbose || config.ShowHeaders {
+			t.Errorf("FollowLocation = %v, InsecureOK = %v, Verbose = %v, ShowHeaders = %v; want all false",
+				config.FollowLocation, config.InsecureOK, global.Verbose, config.ShowHeaders)
+		}
+		if err := parser.Parse([]string{"--no-location", "--location"}); err != nil {
+			t.Fatalf("Parse() failed: %v", err)
+		}
+		if !config.FollowLocation {
+			t.Error("--location after --no-location should enable it again")
+		}
+	})
+
+	t.Run("abbreviations", func(t *testing.T) {
+		global := NewGlobalConfig()
+		parser := NewParameterParser(global)
+		if err := parser.Parse([]string{"--verb", "--user-a", "agent/1", "--no-loc", "--max-r", "5"}); err != nil {
+			t.Fatalf("Parse() failed: %v", err)
+		}
+		config := global.Last
+		if !global.Verbose || config.UserAgent != "agent/1" || config.FollowLocation || config.MaxRedirs != 5 {
+			t.Errorf("Verbose = %v, UserAgent = %q, FollowLocation = %v, MaxRedirs = %d",
+				global.Verbose, config.UserAgent, config.FollowLocation, config.MaxRedirs)
+		}
+		// An exact name is preferred over longer names it abbreviates.
+		if err := parser.Parse([]string{"--parallel"}); err != nil || !global.Parallel {
+			t.Errorf("--parallel: err = %v, Parallel = %v", err, global.Parallel)
+		}
+	})
+
+	errorCases := []struct {
+		flag string
+		want ParameterError
+	}{
+		{"--max", ParamOptionAmbiguous},
+		{"--no-max", ParamOptionAmbiguous},
+		{"--no-user-agent", ParamNoPrefix},
+		{"--no-output", ParamNoPrefix},
+		{"--no-next", ParamNoPrefix},
+		{"--nonsense", ParamOptionUnknown},
+		{"--no-nonsense", ParamOptionUnknown},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.flag, func(t *testing.T) {
+			parser := NewParameterParser(NewGlobalConfig())
+			_, err := parser.ParseOne(tc.flag, "value")
+			if !errors.Is(err, tc.want) {
+				t.Errorf("ParseOne(%q) error = %v; want %q", tc.flag, err, tc.want)
+			}
+		})
+	}
+}
+
 func TestParameterParser_Auth(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -190,6 +806,11 @@ func TestParameterParser_Auth(t *testing.T) {
 			args:     []string{"--anyauth"},
 			expected: AuthAny,
 		},
+		{
+			name:     "no- prefix clears a method",
+			args:     []string{"--basic", "--ntlm", "--no-basic"},
+			expected: AuthNTLM,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -320,4 +941,4 @@ func TestOptionsMap(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
@@ -80,6 +80,12 @@ func (e ParameterError) String() string {
 	}
 }
 
+// Error makes ParameterError usable as an error value; it returns the same
+// text as String.
+func (e ParameterError) Error() string {
+	return e.String()
+}
+
 // HTTPRequest is a translation of the C enum `HttpReq` from
 // curl-src/src/tool_sdecls.h, lines 112-119.
 type HTTPRequest int
@@ -129,6 +135,12 @@ func (m *HTTPRequestManager) Set(req HTTPRequest) error {
 		req.String(), m.request.String())
 }
 
+// Get returns the HTTP request type that has been set, HTTPRequestUnspec if
+// none.
+func (m *HTTPRequestManager) Get() HTTPRequest {
+	return m.request
+}
+
 // CustomRequestHelper provides helpful warnings for the use of custom requests.
 // This is a translation of the C function `customrequest_helper` from
 // curl-src/src/tool_helpers.c, lines 95-119.
@@ -0,0 +1,336 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync/atomic"
+)
+
+// This file contains the Go translation of the driver in
+// curl-src/src/tool_operate.c: reading the default config file, parsing the
+// command line and running every transfer the resulting configuration
+// describes.
+
+// messager returns a Messager that honors the global --silent, --verbose
+// and --show-error settings.
+func (g *GlobalConfig) messager() *Messager {
+	return NewMessager(g.Stderr, g.Silent, g.Verbose, g.ShowError)
+}
+
+// Operate parses the command line arguments into global and performs the
+// transfers they describe. It is the Go equivalent of the C function
+// `operate`. Errors are reported through the Messager as they happen; the
+// returned error is the last one encountered, and ErrorCode turns it into
+// the exit status curl would use.
+func Operate(ctx context.Context, global *GlobalConfig, args []string) error {
+	parser := NewParameterParser(global)
+
+	// The default config file is skipped when -q/--disable is the very
+	// first argument.
+	if len(args) == 0 || (args[0] != "-q" && args[0] != "--disable") {
+		if path, found := FindCurlRC(); found {
+			if err := parser.ParseConfigFile(path); err != nil {
+				reportParseError(global, err)
+				return err
+			}
+		}
+	}
+
+	if err := parser.Parse(args); err != nil {
+		reportParseError(global, err)
+		return err
+	}
+
+	return runAllTransfers(ctx, global)
+}
+
+// reportParseError prints an option error the way curl's `parse_args`
+// does: the detailed reason first, if there is one, then the option and
+// the hint to try --help.
+func reportParseError(global *GlobalConfig, err error) {
+	msg := global.messager()
+	var oe *OptionError
+	if errors.As(err, &oe) && oe.Detail != "" {
+		msg.Errorf("%s", oe.Detail)
+	}
+	msg.Helpf("%v", err)
+}
+
+// runAllTransfers walks the chain of operations and performs every URL in
+// each of them, one after the other or concurrently with --parallel. It is
+// the Go equivalent of the C function `run_all_transfers`. The transfers
+// are created as they are needed, so that large globs are never expanded
+// all at once.
+func runAllTransfers(ctx context.Context, global *GlobalConfig) error {
+	msg := global.messager()
+
+	// --parallel-immediate asks for a fresh connection per transfer rather
+	// than waiting to reuse or multiplex an existing one.
+	queue := newTransferQueue(global, !global.Parallel || !global.ParallelConnect)
+	defer queue.closeIdle()
+
+	first, err := queue.peek()
+	if err != nil {
+		msg.Errorf("%v", err)
+		return err
+	}
+	if first == nil {
+		msg.Helpf("no URL specified")
+		return fmt.Errorf("no URL specified")
+	}
+
+	if global.Parallel {
+		return parallelTransfers(ctx, global, queue)
+	}
+	return serialTransfers(ctx, global, queue)
+}
+
+// serialTransfers performs the transfers one at a time. It is the Go
+// equivalent of the C function `serial_transfers`.
+func serialTransfers(ctx context.Context, global *GlobalConfig, queue *transferQueue) error {
+	msg := global.messager()
+
+	var lastErr error
+	for {
+		t, err := queue.next()
+		if err != nil {
+			msg.Errorf("%v", err)
+			return err
+		}
+		if t == nil {
+			break
+		}
+		err = t.Perform(ctx)
+		postTransfer(global, msg, t, err)
+		if err != nil {
+			lastErr = err
+			if global.FailEarly {
+				break
+			}
+		}
+	}
+	return lastErr
+}
+
+// postTransfer reports the outcome of a finished transfer: its error, if
+// any, and the --write-out output. It corresponds to the C function
+// `post_per_transfer`.
+func postTransfer(global *GlobalConfig, msg *Messager, t *Transfer, err error) {
+	if err != nil {
+		msg.Errorf("%v", err)
+	}
+	if t.Config.WriteOut != "" {
+		// Render into buffers first so the output is written in one go.
+		var stdout, stderr bytes.Buffer
+		WriteOutTo(&stdout, &stderr, t.Config.WriteOut, &WriteOutData{Info: t.Info, Headers: t.Headers, URL: t.URL})
+		global.Stdout.Write(stdout.Bytes())
+		global.Stderr.Write(stderr.Bytes())
+	}
+}
+
+// transferQueue creates the transfers of every operation one at a time,
+// walking the URL globs as it goes. It corresponds to the URL iteration
+// done by the C functions `create_transfer` and `single_transfer`.
+type transferQueue struct {
+	global *GlobalConfig
+	config *OperationConfig // the operation being walked
+	node   int              // the index of the URL in config.URLList
+
+	// The globs of the URL and upload file of the current node, and the
+	// upload file the URLs are paired with.
+	urls, infiles *URLGlob
+	infile        GlobURL
+
+	peeked *Transfer
+
+	// total is the number of transfers of every operation, worked out up
+	// front, and created the number made so far, read by the progress
+	// meter while the queue is walked.
+	total   int64
+	created atomic.Int64
+
+	// share gives the transfers of each operation a common HTTP transport,
+	// so that they reuse connections the way transfers sharing a libcurl
+	// connection cache do.
+	share      bool
+	transports map[*OperationConfig]*http.Transport
+}
+
+func newTransferQueue(global *GlobalConfig, share bool) *transferQueue {
+	return &transferQueue{
+		global:     global,
+		config:     global.First,
+		total:      countTransfers(global),
+		share:      share,
+		transports: make(map[*OperationConfig]*http.Transport),
+	}
+}
+
+// countTransfers returns the number of transfers the operations describe:
+// each upload file of a URL node for each of its URLs. The globs are only
+// parsed, not walked. A node whose glob is bad counts for none, and a
+// count beyond an int64 is capped.
+func countTransfers(global *GlobalConfig) int64 {
+	var total int64
+	for config := global.First; config != nil; config = config.Next {
+		for _, urlConf := range config.URLList {
+			if urlConf.URL == "" {
+				continue
+			}
+			urls, infiles, err := nodeGlobs(urlConf)
+			if err != nil {
+				continue
+			}
+			n, m := urls.Total(), infiles.Total()
+			if m > 0 && n > (math.MaxInt64-total)/m {
+				return math.MaxInt64
+			}
+			total += n * m
+		}
+	}
+	return total
+}
+
+// pending returns the number of transfers not created yet.
+func (q *transferQueue) pending() int64 {
+	return max(q.total-q.created.Load(), 0)
+}
+
+// peek returns the next transfer without taking it from the queue.
+func (q *transferQueue) peek() (*Transfer, error) {
+	if q.peeked == nil {
+		var err error
+		if q.peeked, err = q.create(); err != nil {
+			return nil, err
+		}
+	}
+	return q.peeked, nil
+}
+
+// next returns the next transfer, or nil when there are no more.
+func (q *transferQueue) next() (*Transfer, error) {
+	t, err := q.peek()
+	q.peeked = nil
+	return t, err
+}
+
+// create makes the next transfer. Each upload file of a node is sent to
+// each of its URLs.
+func (q *transferQueue) create() (*Transfer, error) {
+	for q.config != nil {
+		if q.urls == nil {
+			ok, err := q.startNode()
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		urlConf := q.config.URLList[q.node]
+
+		u, ok := q.urls.Next()
+		if !ok {
+			// Every URL got the upload file: go on with the next one.
+			if q.infile, ok = q.infiles.Next(); ok {
+				q.urls.Reset()
+				u, ok = q.urls.Next()
+			}
+		}
+		if !ok {
+			q.urls, q.infiles = nil, nil
+			q.node++
+			continue
+		}
+
+		rawURL := u.URL
+		infile := q.infile.URL
+		if infile != "" && !stdinUpload(infile) {
+			rawURL = addFileNameToURL(rawURL, infile)
+		}
+		// "#N" in the output file name is the value the Nth glob of the
+		// URL took.
+		outfile := urlConf.Outfile
+		if !urlConf.NoGlob && outfile != "" && outfile != "-" {
+			outfile = globMatchURL(outfile, u.Captures)
+		}
+		t := NewTransfer(q.global, q.config, transferURL(q.config, rawURL), outfile)
+		q.created.Add(1)
+		t.UseRemote = urlConf.UseRemote
+		t.Infile = infile
+		if q.share {
+			t.transport = q.transport(q.config)
+		}
+		return t, nil
+	}
+	return nil, nil
+}
+
+// startNode sets up the globs of the current URL node, moving on to the
+// next operation after the last node. It returns false when there is no
+// node to walk yet.
+func (q *transferQueue) startNode() (bool, error) {
+	if q.node >= len(q.config.URLList) {
+		q.config, q.node = q.config.Next, 0
+		return false, nil
+	}
+	urlConf := q.config.URLList[q.node]
+	if urlConf.URL == "" {
+		q.global.messager().Warnf("Got more output options than URLs")
+		q.node++
+		return false, nil
+	}
+
+	urls, infiles, err := nodeGlobs(urlConf)
+	if err != nil {
+		q.node++
+		return false, newTransferError(CurlURLMalformat, "[globbing] %v", err)
+	}
+	q.urls, q.infiles = urls, infiles
+	q.infile, _ = q.infiles.Next()
+	return true, nil
+}
+
+// nodeGlobs returns the globs of the URL and upload file of a URL node.
+// With --globoff, and for stdin uploads, they are taken literally.
+func nodeGlobs(urlConf *URLConfig) (urls, infiles *URLGlob, err error) {
+	urls, infiles = literalGlob(urlConf.URL), literalGlob(urlConf.Infile)
+	if urlConf.NoGlob {
+		return urls, infiles, nil
+	}
+	if urls, err = NewURLGlob(urlConf.URL); err != nil {
+		return nil, nil, err
+	}
+	if urlConf.Infile != "" && !stdinUpload(urlConf.Infile) {
+		if infiles, err = NewURLGlob(urlConf.Infile); err != nil {
+			return nil, nil, err
+		}
+	}
+	return urls, infiles, nil
+}
+
+// transport returns the transport shared by the transfers of config. A
+// config the transport cannot be built for is left for the transfers to
+// report.
+func (q *transferQueue) transport(config *OperationConfig) *http.Transport {
+	transport, ok := q.transports[config]
+	if !ok {
+		transport, _ = newTransport(config)
+		q.transports[config] = transport
+	}
+	return transport
+}
+
+// closeIdle closes the idle connections of the shared transports once the
+// transfers are over.
+func (q *transferQueue) closeIdle() {
+	for _, transport := range q.transports {
+		if transport != nil {
+			transport.CloseIdleConnections()
+		}
+	}
+}
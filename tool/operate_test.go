@@ -0,0 +1,549 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolateCurlRC points the config file search at an empty directory so a
+// developer's own .curlrc cannot influence the tests.
+func isolateCurlRC(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("CURL_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	return dir
+}
+
+func TestOperate(t *testing.T) {
+	srv := newTestServer(t)
+
+	t.Run("transfers and write-out", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, stdout, _ := newTestGlobal()
+		out := filepath.Join(t.TempDir(), "out.txt")
+		args := []string{
+			"-o", out, srv.URL + "/hello",
+			srv.URL + "/echo",
+			"-w", "[%{http_code}]",
+		}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if content, _ := os.ReadFile(out); string(content) != "hello world" {
+			t.Errorf("output file = %q; want %q", content, "hello world")
+		}
+		if !strings.HasPrefix(stdout.String(), "[200]GET /echo") || !strings.HasSuffix(stdout.String(), "[200]") {
+			t.Errorf("stdout = %q", stdout.String())
+		}
+	})
+
+	t.Run("reads curlrc", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		rc := "user-agent = \"from-rc\"\n"
+		if err := os.WriteFile(filepath.Join(dir, ".curlrc"), []byte(rc), 0644); err != nil {
+			t.Fatal(err)
+		}
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{srv.URL + "/echo"}); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if !strings.Contains(stdout.String(), "ua=from-rc") {
+			t.Errorf("stdout = %q; want the .curlrc user agent", stdout.String())
+		}
+	})
+
+	t.Run("disable skips curlrc", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		rc := "user-agent = \"from-rc\"\n"
+		if err := os.WriteFile(filepath.Join(dir, ".curlrc"), []byte(rc), 0644); err != nil {
+			t.Fatal(err)
+		}
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{"-q", srv.URL + "/echo"}); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if strings.Contains(stdout.String(), "from-rc") {
+			t.Errorf("stdout = %q; -q should skip .curlrc", stdout.String())
+		}
+	})
+
+	t.Run("config file errors", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		path := filepath.Join(dir, "env.rc")
+		if err := os.WriteFile(path, []byte("silent\nmax-time = soon\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		global, _, stderr := newTestGlobal()
+		err := Operate(context.Background(), global, []string{"-K", path, srv.URL + "/hello"})
+		if code := ErrorCode(err); code != CurlFailedInit {
+			t.Fatalf("ErrorCode(Operate()) = %d; want %d", code, CurlFailedInit)
+		}
+		want := "curl: " + path + ":2: option --max-time: expected a proper numerical parameter\n"
+		if !strings.HasPrefix(stderr.String(), want) {
+			t.Errorf("stderr = %q; want prefix %q", stderr.String(), want)
+		}
+	})
+
+	t.Run("reports transfer errors", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, _, stderr := newTestGlobal()
+		err := Operate(context.Background(), global, []string{"-f", srv.URL + "/missing"})
+		if code := ErrorCode(err); code != CurlHTTPReturnedError {
+			t.Fatalf("ErrorCode(Operate()) = %d; want %d", code, CurlHTTPReturnedError)
+		}
+		if !strings.Contains(stderr.String(), "curl: (22) The requested URL returned error: 404") {
+			t.Errorf("stderr = %q", stderr.String())
+		}
+	})
+
+	t.Run("write-out streams and onerror", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, stdout, stderr := newTestGlobal()
+		args := []string{
+			"-s", "-o", filepath.Join(t.TempDir(), "out.txt"),
+			"-w", `%{stderr}%{header_json}%{stdout}%{json}\n%{onerror}failed: %{exitcode}\n`,
+			srv.URL + "/hello", "-f", srv.URL + "/missing",
+		}
+		if err := Operate(context.Background(), global, args); err == nil {
+			t.Fatal("Operate() should fail")
+		}
+		lines := strings.Split(strings.TrimSuffix(stdout.String(), "\n"), "\n")
+		if len(lines) != 3 || lines[2] != "failed: 22" {
+			t.Fatalf("stdout = %q; want two JSON lines and one failure", stdout.String())
+		}
+		var info map[string]interface{}
+		if err := json.Unmarshal([]byte(lines[0]), &info); err != nil || info["http_code"] != float64(200) {
+			t.Errorf("first JSON line = %q (%v)", lines[0], err)
+		}
+		if !strings.Contains(stderr.String(), `"Content-Type":["text/plain; charset=utf-8"]`) {
+			t.Errorf("stderr = %q; want the response headers", stderr.String())
+		}
+	})
+
+	t.Run("write-out headers and URL parts", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, stdout, _ := newTestGlobal()
+		args := []string{
+			"-L", "-o", filepath.Join(t.TempDir(), "out.txt"),
+			"-w", "%{url.path} %{urle.path} %{urle.port} %header{content-type}",
+			srv.URL + "/redirect",
+		}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		want := "/redirect /hello " + srv.URL[strings.LastIndex(srv.URL, ":")+1:] + " text/plain"
+		if stdout.String() != want {
+			t.Errorf("stdout = %q; want %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("silent hides errors", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, _, stderr := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{"-s", "-f", srv.URL + "/missing"}); err == nil {
+			t.Fatal("Operate() should fail")
+		}
+		if stderr.Len() != 0 {
+			t.Errorf("stderr should be empty with --silent, got %q", stderr.String())
+		}
+	})
+
+	t.Run("posts data", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, stdout, _ := newTestGlobal()
+		args := []string{"-d", "a=1", "--data-urlencode", "b=x y", srv.URL + "/body"}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		want := "POST type=application/x-www-form-urlencoded accept=*/* body=a=1&b=x%20y"
+		if stdout.String() != want {
+			t.Errorf("stdout = %q; want %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("get with data", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, stdout, _ := newTestGlobal()
+		args := []string{"-G", "-d", "a=1", "--url-query", "b=x y", srv.URL + "/echo?q=0"}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if want := "GET /echo?q=0&a=1&b=x%20y "; !strings.HasPrefix(stdout.String(), want) {
+			t.Errorf("stdout = %q; want prefix %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("verbatim url-query with a space", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, stdout, _ := newTestGlobal()
+		args := []string{"--url-query", "+raw=a b", srv.URL + "/echo"}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if want := "GET /echo?raw=a+b "; !strings.HasPrefix(stdout.String(), want) {
+			t.Errorf("stdout = %q; want prefix %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("posts json", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, stdout, _ := newTestGlobal()
+		args := []string{
+			"--json", `{"a":1,`, "--json", `"b":2}`, srv.URL + "/body",
+			"--next",
+			"--json", "[]", "-H", "Accept: text/plain", "-H", "Content-Type: application/vnd.api+json", srv.URL + "/body",
+		}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		want := `POST type=application/json accept=application/json body={"a":1,"b":2}` +
+			`POST type=application/vnd.api+json accept=text/plain body=[]`
+		if stdout.String() != want {
+			t.Errorf("stdout = %q; want %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("posts form", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		upload := filepath.Join(dir, "upload.txt")
+		if err := os.WriteFile(upload, []byte("uploaded"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		global, stdout, _ := newTestGlobal()
+		args := []string{"-F", "name=value", "-F", "file=@" + upload, "--form-string", "raw=@not;a file", srv.URL + "/form"}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		want := "name=value\nfile=uploaded (upload.txt)\nraw=@not;a file\n"
+		if stdout.String() != want {
+			t.Errorf("stdout = %q; want %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("posts encoded form", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		text := filepath.Join(dir, "text.txt")
+		if err := os.WriteFile(text, []byte("caf\xc3\xa9 = coffee"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		global, stdout, _ := newTestGlobal()
+		// The quoted-printable file has no known size and is sent chunked.
+		args := []string{"-F", "text=<" + text + ";encoder=quoted-printable", srv.URL + "/form"}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if want := "text=caf\xc3\xa9 = coffee\n"; stdout.String() != want {
+			t.Errorf("stdout = %q; want %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("uploads files", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		for _, name := range []string{"a.txt", "b.txt"} {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("data of "+name), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		global, stdout, _ := newTestGlobal()
+		args := []string{"-T", filepath.Join(dir, "{a,b}.txt"), srv.URL + "/upload/", "-T", filepath.Join(dir, "a.txt"), srv.URL + "/upload/named"}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		want := "PUT /upload/a.txt length=13 chunked=false body=data of a.txt\n" +
+			"PUT /upload/b.txt length=13 chunked=false body=data of b.txt\n" +
+			"PUT /upload/named length=13 chunked=false body=data of a.txt\n"
+		if stdout.String() != want {
+			t.Errorf("stdout = %q; want %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("uploads stdin chunked", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		stdinFile := filepath.Join(dir, "stdin")
+		if err := os.WriteFile(stdinFile, []byte("from stdin"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for _, name := range []string{"-", "."} {
+			stdin, err := os.Open(stdinFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			saved := os.Stdin
+			os.Stdin = stdin
+			global, stdout, _ := newTestGlobal()
+			err = Operate(context.Background(), global, []string{"-T", name, srv.URL + "/upload/"})
+			os.Stdin = saved
+			stdin.Close()
+			if err != nil {
+				t.Fatalf("-T %s: Operate() failed: %v", name, err)
+			}
+			if want := "PUT /upload/ length=-1 chunked=true body=from stdin\n"; stdout.String() != want {
+				t.Errorf("-T %s: stdout = %q; want %q", name, stdout.String(), want)
+			}
+		}
+	})
+
+	t.Run("upload file missing", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, _, stderr := newTestGlobal()
+		err := Operate(context.Background(), global, []string{"-T", "/no/such/file", srv.URL + "/upload/"})
+		if code := ErrorCode(err); code != CurlReadError {
+			t.Errorf("ErrorCode(Operate()) = %d; want %d", code, CurlReadError)
+		}
+		if !strings.Contains(stderr.String(), "Can't open '/no/such/file'") {
+			t.Errorf("stderr = %q", stderr.String())
+		}
+	})
+
+	t.Run("resumes downloads", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		out := filepath.Join(dir, "out.txt")
+		testCases := []struct {
+			path     string
+			existing string
+			want     CurlCode
+			content  string
+		}{
+			{"/ranged", "hello", CurlOK, "hello world"},
+			{"/ranged", "hello world", CurlOK, "hello world"}, // 416
+			{"/hello", "hello", CurlRangeError, "hello"},
+			{"/hello", "hello world", CurlOK, "hello world"},
+		}
+		for _, tc := range testCases {
+			if err := os.WriteFile(out, []byte(tc.existing), 0644); err != nil {
+				t.Fatal(err)
+			}
+			global, _, _ := newTestGlobal()
+			err := Operate(context.Background(), global, []string{"-f", "-C", "-", "-o", out, srv.URL + tc.path})
+			if code := ErrorCode(err); code != tc.want {
+				t.Errorf("%s from %q: ErrorCode(Operate()) = %d; want %d", tc.path, tc.existing, code, tc.want)
+			}
+			if got, _ := os.ReadFile(out); string(got) != tc.content {
+				t.Errorf("%s from %q: file = %q; want %q", tc.path, tc.existing, got, tc.content)
+			}
+		}
+
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{"-C", "6", srv.URL + "/ranged"}); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if stdout.String() != "world" {
+			t.Errorf("stdout = %q; want %q", stdout.String(), "world")
+		}
+	})
+
+	t.Run("writes ranges at their offsets", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		for _, spec := range []string{"0-1,6-7", "6-7"} {
+			out := filepath.Join(dir, "out.txt")
+			os.Remove(out)
+			global, _, _ := newTestGlobal()
+			args := []string{"--sparse-ranges", "-r", spec, "-o", out, srv.URL + "/ranged"}
+			if err := Operate(context.Background(), global, args); err != nil {
+				t.Fatalf("Operate(%q) failed: %v", args, err)
+			}
+			want := "\x00\x00\x00\x00\x00\x00wo\x00\x00\x00"
+			if spec == "0-1,6-7" {
+				want = "he" + want[2:]
+			}
+			if got, _ := os.ReadFile(out); string(got) != want {
+				t.Errorf("-r %s: file = %q; want %q", spec, got, want)
+			}
+		}
+
+		// Without --sparse-ranges the multipart body is output as it is.
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{"-r", "0-1,6-7", srv.URL + "/ranged"}); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if !strings.Contains(stdout.String(), "Content-Range: bytes 6-7/11\r\n") {
+			t.Errorf("stdout = %q; want the multipart/byteranges body", stdout.String())
+		}
+	})
+
+	t.Run("resumes uploads", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		upload := filepath.Join(dir, "up.txt")
+		if err := os.WriteFile(upload, []byte("0123456789"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{"-C", "4", "-T", upload, srv.URL + "/upload/"}); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if want := "PUT /upload/up.txt length=6 chunked=false range=bytes 4-9/10 body=456789\n"; stdout.String() != want {
+			t.Errorf("stdout = %q; want %q", stdout.String(), want)
+		}
+
+		global, _, _ = newTestGlobal()
+		err := Operate(context.Background(), global, []string{"-C", "10", "-T", upload, srv.URL + "/upload/"})
+		if code := ErrorCode(err); code != CurlPartialFile {
+			t.Errorf("ErrorCode(Operate()) = %d; want %d", code, CurlPartialFile)
+		}
+	})
+
+	t.Run("form file missing", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, _, _ := newTestGlobal()
+		err := Operate(context.Background(), global, []string{"-F", "f=@/no/such/file", srv.URL + "/form"})
+		if code := ErrorCode(err); code != CurlReadError {
+			t.Errorf("ErrorCode(Operate()) = %d; want %d", code, CurlReadError)
+		}
+	})
+
+	t.Run("glob output names", func(t *testing.T) {
+		dir := isolateCurlRC(t)
+		global, _, _ := newTestGlobal()
+		args := []string{"-o", filepath.Join(dir, "#1_#2.txt"), srv.URL + "/{hello,echo}?n=[08-09]"}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		for _, name := range []string{"hello_08.txt", "hello_09.txt", "echo_08.txt", "echo_09.txt"} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+				t.Errorf("output %s: %v", name, err)
+			}
+		}
+		if got, _ := os.ReadFile(filepath.Join(dir, "echo_09.txt")); !strings.HasPrefix(string(got), "GET /echo?n=09 ") {
+			t.Errorf("echo_09.txt = %q", got)
+		}
+	})
+
+	t.Run("huge globs are walked lazily", func(t *testing.T) {
+		isolateCurlRC(t)
+		for _, parallel := range [][]string{nil, {"-Z", "--parallel-max", "2"}} {
+			global, _, _ := newTestGlobal()
+			args := append(parallel, "-s", "-f", "--fail-early", srv.URL+"/missing?n=[1-1000000000]")
+			if code := ErrorCode(Operate(context.Background(), global, args)); code != CurlHTTPReturnedError {
+				t.Errorf("Operate(%q) code = %d; want %d", args, code, CurlHTTPReturnedError)
+			}
+		}
+
+		// A bad glob is reported when its URL is reached.
+		global, stdout, _ := newTestGlobal()
+		err := Operate(context.Background(), global, []string{srv.URL + "/hello", srv.URL + "/[1-"})
+		if err == nil || stdout.String() != "hello world" {
+			t.Errorf("Operate() = %v, stdout = %q", err, stdout.String())
+		}
+	})
+
+	t.Run("globoff and IPv6", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, stdout, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{"-g", srv.URL + "/echo?a=[1-2]"}); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		if !strings.HasPrefix(stdout.String(), "GET /echo?a=[1-2] ") {
+			t.Errorf("stdout = %q", stdout.String())
+		}
+
+		global, _, stderr := newTestGlobal()
+		err := Operate(context.Background(), global, []string{srv.URL + "/{a,{b}}"})
+		if code := ErrorCode(err); code != CurlURLMalformat {
+			t.Errorf("ErrorCode(Operate()) = %d; want %d", code, CurlURLMalformat)
+		}
+		if want := "[globbing] nested brace in column "; !strings.Contains(stderr.String(), want) {
+			t.Errorf("stderr = %q; want %q", stderr.String(), want)
+		}
+
+		ln, err := net.Listen("tcp", "[::1]:0")
+		if err != nil {
+			t.Skipf("no IPv6: %v", err)
+		}
+		srv6 := httptest.NewUnstartedServer(srv.Config.Handler)
+		srv6.Listener.Close()
+		srv6.Listener = ln
+		srv6.Start()
+		defer srv6.Close()
+		global, stdout, _ = newTestGlobal()
+		if err := Operate(context.Background(), global, []string{srv6.URL + "/{hello,hello}"}); err != nil {
+			t.Fatalf("Operate(%s) failed: %v", srv6.URL, err)
+		}
+		if stdout.String() != "hello worldhello world" {
+			t.Errorf("stdout = %q", stdout.String())
+		}
+	})
+
+	t.Run("next operation", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, stdout, _ := newTestGlobal()
+		args := []string{
+			"-A", "first", srv.URL + "/echo",
+			"--next",
+			"-d", "a=b", srv.URL + "/echo",
+			"--next",
+			"-I", srv.URL + "/hello",
+		}
+		if err := Operate(context.Background(), global, args); err != nil {
+			t.Fatalf("Operate() failed: %v", err)
+		}
+		out := stdout.String()
+		get := strings.Index(out, "GET /echo ua=first")
+		post := strings.Index(out, "POST /echo")
+		head := strings.Index(out, "HTTP/1.1 200")
+		if get < 0 || post < get || head < post {
+			t.Errorf("stdout = %q; want GET, POST and HEAD output in order", out)
+		}
+		if strings.Contains(out[post:], "ua=first") {
+			t.Errorf("the user agent leaked into the next operation: %q", out)
+		}
+	})
+
+	t.Run("option errors", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, _, stderr := newTestGlobal()
+		err := Operate(context.Background(), global, []string{"--next", srv.URL})
+		if code := ErrorCode(err); code != CurlFailedInit {
+			t.Fatalf("ErrorCode(Operate()) = %d; want %d", code, CurlFailedInit)
+		}
+		want := "curl: missing URL before --next\n" +
+			"curl: option --next: is badly used here\n" +
+			"curl: try 'curl --help' or 'curl --manual' for more information\n"
+		if stderr.String() != want {
+			t.Errorf("stderr = %q; want %q", stderr.String(), want)
+		}
+	})
+
+	t.Run("no URL", func(t *testing.T) {
+		isolateCurlRC(t)
+		global, _, stderr := newTestGlobal()
+		err := Operate(context.Background(), global, []string{"-v"})
+		if code := ErrorCode(err); code != CurlFailedInit {
+			t.Fatalf("ErrorCode(Operate()) = %d; want %d", code, CurlFailedInit)
+		}
+		if !strings.Contains(stderr.String(), "curl: no URL specified") {
+			t.Errorf("stderr = %q", stderr.String())
+		}
+	})
+}
+
+func TestCountTransfers(t *testing.T) {
+	testCases := []struct {
+		args []string
+		want int64
+	}{
+		{[]string{"http://h/a", "http://h/{b,c}"}, 3},
+		{[]string{"-T", "{x,y}", "http://h/[1-3]/"}, 6},
+		{[]string{"-g", "http://h/[1-3]", "--next", "http://h/[1-2]"}, 3},
+		{[]string{"http://h/{bad", "http://h/ok"}, 1},
+		{[]string{"http://h/[0-9223372036854775807]", "http://h/ok"}, 1},
+		{[]string{"http://h/[1-3000000000][1-3000000000]", "http://h/[1-3000000000][1-3000000000]"}, math.MaxInt64},
+	}
+	for _, tc := range testCases {
+		global := NewGlobalConfig()
+		if err := NewParameterParser(global).Parse(tc.args); err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tc.args, err)
+		}
+		if got := countTransfers(global); got != tc.want {
+			t.Errorf("countTransfers(%q) = %d; want %d", tc.args, got, tc.want)
+		}
+	}
+}
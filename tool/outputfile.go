@@ -14,6 +14,7 @@ const (
 	ClobberDefault ClobberMode = iota // Default behavior (overwrite, unless from Content-Disposition)
 	ClobberAlways                     // Always overwrite
 	ClobberNever                      // Never overwrite, try numeric suffixes
+	ClobberAppend                     // Append to an existing file, to resume it (-C)
 )
 
 // CreateOutputFile creates/opens a local file for writing, handling different
@@ -21,6 +22,13 @@ const (
 // `tool_create_output_file` from curl-src/src/tool_cb_wrt.c.
 // It returns the opened file, the final filename used, and any error.
 func CreateOutputFile(filename string, mode ClobberMode, isCdFilename bool) (*os.File, string, error) {
+	if mode == ClobberAppend {
+		// Resumed downloads continue the file, like the fopen(..., "ab")
+		// in the C `single_transfer`.
+		file, err := os.OpenFile(filename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		return file, filename, err
+	}
+
 	// Determine if we should overwrite the file.
 	shouldClobber := (mode == ClobberAlways) || (mode == ClobberDefault && !isCdFilename)
 
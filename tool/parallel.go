@@ -0,0 +1,216 @@
+package tool
+
+import (
+	"context"
+	"io"
+	"math"
+	"sync"
+	"time"
+)
+
+// This file contains the Go translation of the parallel transfer logic in
+// curl-src/src/tool_operate.c (`parallel_transfers` and
+// `add_parallel_transfers`). Where the C code drives a multi handle from a
+// single thread, the Go version runs each transfer in its own goroutine and
+// limits how many run at once.
+
+const (
+	parallelDefault  = 50  // PARALLEL_DEFAULT, the default --parallel-max
+	parallelMaxLimit = 300 // MAX_PARALLEL, the highest --parallel-max accepted
+)
+
+// lockedWriter serializes writes to an underlying writer so that output
+// from concurrent transfers is never interleaved within a single write.
+type lockedWriter struct {
+	mu *sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// parallelScheduler holds the state of a parallel run.
+type parallelScheduler struct {
+	global *GlobalConfig
+	msg    *Messager
+	cancel context.CancelFunc
+	queue  *transferQueue
+
+	mu       sync.Mutex
+	running  map[*Transfer]bool
+	finished TransferStats // the finished transfers, added up
+	aborted  bool
+	firstErr error
+	lastErr  error
+}
+
+// parallelTransfers performs the transfers of the queue concurrently,
+// running at most global.ParallelMax of them at any time and starting them
+// in order. With --fail-early, the first failure cancels the transfers
+// still running and nothing further is started. It returns the first error
+// when failing early and the last one otherwise, like the serial code path.
+func parallelTransfers(ctx context.Context, global *GlobalConfig, queue *transferQueue) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Transfers write to the shared streams concurrently.
+	var outMu sync.Mutex
+	stdout, stderr := global.Stdout, global.Stderr
+	global.Stdout = &lockedWriter{mu: &outMu, w: stdout}
+	global.Stderr = &lockedWriter{mu: &outMu, w: stderr}
+	defer func() {
+		global.Stdout, global.Stderr = stdout, stderr
+	}()
+
+	s := &parallelScheduler{
+		global:  global,
+		msg:     global.messager(),
+		cancel:  cancel,
+		queue:   queue,
+		running: make(map[*Transfer]bool),
+	}
+
+	stopMeter := s.startProgressMeter()
+	defer stopMeter()
+
+	max := global.ParallelMax
+	if max < 1 {
+		max = parallelDefault
+	}
+	slots := make(chan struct{}, max)
+	var wg sync.WaitGroup
+	var queueErr error
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		t, err := queue.next()
+		if err != nil {
+			// A URL that cannot be walked ends the run once the transfers
+			// already started are over.
+			s.msg.Errorf("%v", err)
+			queueErr = err
+			break
+		}
+		if t == nil {
+			break
+		}
+
+		s.mu.Lock()
+		s.running[t] = true
+		s.mu.Unlock()
+
+		wg.Add(1)
+		go func(t *Transfer) {
+			defer wg.Done()
+			err := t.Perform(ctx)
+			<-slots
+			s.finish(t, err)
+		}(t)
+	}
+	wg.Wait()
+
+	if queueErr != nil {
+		return queueErr
+	}
+	if s.aborted {
+		return s.firstErr
+	}
+	return s.lastErr
+}
+
+// finish records the outcome of a transfer and reports it.
+func (s *parallelScheduler) finish(t *Transfer, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Only the totals of finished transfers are kept, so that a long run
+	// does not hold on to every transfer.
+	stats := t.Stats()
+	s.finished.DLTotal += stats.DLTotal
+	s.finished.DLNow += stats.DLNow
+	s.finished.ULTotal += stats.ULTotal
+	s.finished.ULNow += stats.ULNow
+	s.finished.Transfers++
+	s.finished.Done = true
+	delete(s.running, t)
+
+	if err != nil && s.aborted && ErrorCode(err) == CurlAbortedByCallback {
+		// Cancelled by --fail-early because of another transfer's failure.
+		return
+	}
+	postTransfer(s.global, s.msg, t, err)
+	if err == nil {
+		return
+	}
+	s.lastErr = err
+	if s.firstErr == nil {
+		s.firstErr = err
+	}
+	if s.global.FailEarly && !s.aborted {
+		s.aborted = true
+		s.cancel()
+	}
+}
+
+// stats returns the progress of every transfer started so far, with the
+// finished ones added up in a single entry, and an entry for the ones
+// still queued.
+func (s *parallelScheduler) stats() []TransferStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := make([]TransferStats, 0, len(s.running)+2)
+	if s.finished.Transfers > 0 {
+		stats = append(stats, s.finished)
+	}
+	for t := range s.running {
+		stats = append(stats, t.Stats())
+	}
+	if s.aborted {
+		// Nothing more is started after --fail-early stopped the run.
+		return stats
+	}
+	if pending := s.queue.pending(); pending > 0 {
+		stats = append(stats, TransferStats{Queued: true, Transfers: int(min(pending, math.MaxInt32))})
+	}
+	return stats
+}
+
+// startProgressMeter renders the parallel progress meter to stderr until
+// the returned function is called, which also renders the final line. The
+// meter is not shown with --silent.
+func (s *parallelScheduler) startProgressMeter() func() {
+	if s.global.Silent {
+		return func() {}
+	}
+	bar := NewProgressBar(s.global.Stderr)
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				bar.Render(s.stats(), false)
+			case <-stop:
+				bar.Render(s.stats(), true)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-finished
+	}
+}
@@ -0,0 +1,185 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// concurrencyServer counts how many requests it is serving at once. Each
+// request is held for the given delay.
+type concurrencyServer struct {
+	*httptest.Server
+	current, peak atomic.Int32
+}
+
+func newConcurrencyServer(t *testing.T, delay time.Duration) *concurrencyServer {
+	t.Helper()
+	cs := &concurrencyServer{}
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := cs.current.Add(1)
+		defer cs.current.Add(-1)
+		for {
+			peak := cs.peak.Load()
+			if n <= peak || cs.peak.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		if r.URL.Path == "/fail" {
+			http.NotFound(w, r)
+			return
+		}
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	t.Cleanup(cs.Close)
+	return cs
+}
+
+func TestParallelTransfers(t *testing.T) {
+	t.Run("runs transfers concurrently", func(t *testing.T) {
+		srv := newConcurrencyServer(t, 200*time.Millisecond)
+		global, stdout, _ := newTestGlobal()
+		global.Parallel = true
+		global.Silent = true
+		for i := 0; i < 5; i++ {
+			handleURL(nil, global.Last, fmt.Sprintf("%s/%d", srv.URL, i))
+		}
+
+		start := time.Now()
+		if err := runAllTransfers(context.Background(), global); err != nil {
+			t.Fatalf("runAllTransfers() failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 900*time.Millisecond {
+			t.Errorf("5 transfers of 200ms took %v; they did not run in parallel", elapsed)
+		}
+		if srv.peak.Load() < 2 {
+			t.Errorf("peak concurrency = %d; want more than 1", srv.peak.Load())
+		}
+		for i := 0; i < 5; i++ {
+			if !strings.Contains(stdout.String(), fmt.Sprintf("/%d", i)) {
+				t.Errorf("output %q is missing transfer %d", stdout.String(), i)
+			}
+		}
+	})
+
+	t.Run("honors parallel-max", func(t *testing.T) {
+		srv := newConcurrencyServer(t, 50*time.Millisecond)
+		global, _, _ := newTestGlobal()
+		global.Parallel = true
+		global.ParallelMax = 2
+		global.Silent = true
+		for i := 0; i < 6; i++ {
+			handleURL(nil, global.Last, fmt.Sprintf("%s/%d", srv.URL, i))
+		}
+
+		if err := runAllTransfers(context.Background(), global); err != nil {
+			t.Fatalf("runAllTransfers() failed: %v", err)
+		}
+		if peak := srv.peak.Load(); peak > 2 {
+			t.Errorf("peak concurrency = %d; want at most 2", peak)
+		}
+	})
+
+	t.Run("fail early", func(t *testing.T) {
+		srv := newConcurrencyServer(t, 2*time.Second)
+		global, _, stderr := newTestGlobal()
+		global.Parallel = true
+		global.FailEarly = true
+		global.Silent = true
+		global.ShowError = true
+		global.Last.FailOnError = true
+		handleURL(nil, global.Last, srv.URL+"/slow1")
+		handleURL(nil, global.Last, srv.URL+"/fail")
+		handleURL(nil, global.Last, srv.URL+"/slow2")
+
+		start := time.Now()
+		err := runAllTransfers(context.Background(), global)
+		if code := ErrorCode(err); code != CurlHTTPReturnedError {
+			t.Errorf("ErrorCode() = %d; want %d", code, CurlHTTPReturnedError)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("--fail-early took %v; the other transfers were not aborted", elapsed)
+		}
+		if strings.Count(stderr.String(), "curl: (") != 1 {
+			t.Errorf("stderr = %q; want only the first error reported", stderr.String())
+		}
+	})
+
+	t.Run("progress meter", func(t *testing.T) {
+		srv := newConcurrencyServer(t, 0)
+		global, _, stderr := newTestGlobal()
+		global.Parallel = true
+		handleURL(nil, global.Last, srv.URL+"/a")
+		handleURL(nil, global.Last, srv.URL+"/b")
+
+		if err := runAllTransfers(context.Background(), global); err != nil {
+			t.Fatalf("runAllTransfers() failed: %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(stderr.String()), "\n")
+		fields := strings.Fields(lines[len(lines)-1])
+		if len(fields) < 6 || fields[4] != "2" || fields[5] != "0" {
+			t.Errorf("final meter line = %q; want 2 transfers, none live", lines[len(lines)-1])
+		}
+	})
+}
+
+func TestParallelScheduler_Stats(t *testing.T) {
+	global := NewGlobalConfig()
+	if err := NewParameterParser(global).Parse([]string{"http://example.com/[1-10]"}); err != nil {
+		t.Fatal(err)
+	}
+	queue := newTransferQueue(global, true)
+	s := &parallelScheduler{global: global, queue: queue, running: make(map[*Transfer]bool)}
+
+	// Three transfers started: one still running and two finished.
+	for i := 0; i < 3; i++ {
+		tr, err := queue.next()
+		if err != nil || tr == nil {
+			t.Fatalf("next() = %v, %v", tr, err)
+		}
+		s.running[tr] = true
+		if i > 0 {
+			delete(s.running, tr)
+			s.finished.Transfers++
+			s.finished.Done = true
+		}
+	}
+
+	stats := s.stats()
+	if len(stats) != 3 {
+		t.Fatalf("stats() = %+v; want finished, running and queued entries", stats)
+	}
+	if queued := stats[2]; !queued.Queued || queued.Transfers != 7 {
+		t.Errorf("queued entry = %+v; want 7 queued transfers", queued)
+	}
+}
+
+func TestLockedWriter(t *testing.T) {
+	var mu sync.Mutex
+	var sb strings.Builder
+	w := &lockedWriter{mu: &mu, w: &sb}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Write([]byte("0123456789"))
+		}()
+	}
+	wg.Wait()
+	if sb.Len() != 100 {
+		t.Errorf("wrote %d bytes; want 100", sb.Len())
+	}
+}
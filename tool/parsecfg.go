@@ -2,8 +2,11 @@ package tool
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"unicode"
 )
@@ -12,14 +15,27 @@ import (
 type ConfigEntry struct {
 	Option    string
 	Parameter string
+	File      string // name of the config file, as given to ParseConfig
+	Line      int    // line number of the option in the file, starting at 1
+}
+
+// ConfigSyntaxError reports a line of a config file that cannot be parsed.
+type ConfigSyntaxError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *ConfigSyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
 }
 
 // unslashQuote is a helper function that translates the C function `unslashquote`
 // from curl-src/src/tool_parsecfg.c, lines 74-101.
 // It parses a string, handling backslash-escaped characters, and stops at the
-// first non-escaped double quote. It returns the unquoted string and the
-// remainder of the input string.
-func unslashQuote(line string) (string, string) {
+// first non-escaped double quote. It returns the unquoted string, the
+// remainder of the input string and whether the closing quote was found.
+func unslashQuote(line string) (string, string, bool) {
 	var sb strings.Builder
 	var i int
 	for i = 0; i < len(line); i++ {
@@ -43,27 +59,46 @@ func unslashQuote(line string) (string, string) {
 			}
 		} else if char == '"' {
 			// End of quoted string
-			return sb.String(), line[i+1:]
+			return sb.String(), line[i+1:], true
 		} else {
 			sb.WriteByte(char)
 		}
 	}
 	// Reached end of line without a closing quote
-	return sb.String(), ""
+	return sb.String(), "", false
+}
+
+// continuesLine reports whether a config file line ends with a backslash
+// that joins it with the next line. A doubled backslash is an escaped one.
+func continuesLine(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, "\\"))
+	return n%2 == 1
 }
 
 // ParseConfig reads from an io.Reader and parses it as a curl config file.
 // It is a translation of the C function `parseconfig` from
 // curl-src/src/tool_parsecfg.c, lines 105-274.
 // It returns a slice of ConfigEntry structs or an error.
-func ParseConfig(reader io.Reader) ([]ConfigEntry, error) {
+//
+// filename is recorded in the entries and used in messages. A line ending
+// with a backslash continues on the next one. Like curl, an unquoted
+// parameter ends at the first white space, and if anything but a comment
+// follows it a warning is printed through msg, which may be nil. A quoted
+// parameter without its closing quote is a *ConfigSyntaxError.
+func ParseConfig(reader io.Reader, filename string, msg *Messager) ([]ConfigEntry, error) {
 	var entries []ConfigEntry
 	scanner := bufio.NewScanner(reader)
 	lineno := 0
 
 	for scanner.Scan() {
 		lineno++
-		line := strings.TrimSpace(scanner.Text())
+		start := lineno
+		raw := strings.TrimRight(scanner.Text(), "\r")
+		for continuesLine(raw) && scanner.Scan() {
+			lineno++
+			raw = raw[:len(raw)-1] + strings.TrimRight(scanner.Text(), "\r")
+		}
+		line := strings.TrimSpace(raw)
 
 		// Skip empty lines and comments
 		if line == "" || strings.HasPrefix(line, "#") {
@@ -100,20 +135,29 @@ func ParseConfig(reader io.Reader) ([]ConfigEntry, error) {
 				paramPart = paramPart[paramStartIndex:]
 				if strings.HasPrefix(paramPart, "\"") {
 					// Quoted parameter
-					parameter, _ = unslashQuote(paramPart[1:])
+					var closed bool
+					parameter, _, closed = unslashQuote(paramPart[1:])
+					if !closed {
+						return nil, &ConfigSyntaxError{File: filename, Line: start,
+							Msg: fmt.Sprintf("unterminated quoted parameter for '%s'", option)}
+					}
 				} else {
 					// Unquoted parameter is the first word
 					endParamIndex := strings.IndexFunc(paramPart, unicode.IsSpace)
 					if endParamIndex != -1 {
 						parameter = paramPart[:endParamIndex]
-						// The C code warns about unquoted whitespace here.
+						rest := strings.TrimSpace(paramPart[endParamIndex:])
+						if rest != "" && !strings.HasPrefix(rest, "#") && msg != nil {
+							msg.Warnf("%s:%d: warning: '%s' uses unquoted white space in the line that may cause side-effects!",
+								filename, start, option)
+						}
 					} else {
 						parameter = paramPart
 					}
 				}
 			}
 		}
-		entries = append(entries, ConfigEntry{Option: option, Parameter: parameter})
+		entries = append(entries, ConfigEntry{Option: option, Parameter: parameter, File: filename, Line: start})
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -121,4 +165,65 @@ func ParseConfig(reader io.Reader) ([]ConfigEntry, error) {
 	}
 
 	return entries, nil
-}
\ No newline at end of file
+}
+
+// ParseConfigFile reads the config file at path, "-" meaning stdin, and
+// applies its options through the parser. Together with ParseConfig it is
+// the Go equivalent of the C function `parseconfig`. Options without leading
+// dashes are taken as long options. A config file may load another one with
+// --config; loading a file that is already being read is an error, as it
+// would never end.
+//
+// An option that fails is returned as an *OptionError carrying the file name
+// and line number.
+func (p *ParameterParser) ParseConfigFile(path string) error {
+	name, key := path, path
+	var reader io.Reader
+	if path == "-" {
+		name = "<stdin>"
+		reader = os.Stdin
+	} else {
+		if abs, err := filepath.Abs(path); err == nil {
+			key = abs
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return paramErrorf(ParamReadError, "cannot read config from '%s'", path)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	for _, open := range p.configFiles {
+		if open == key {
+			return paramErrorf(ParamBadUse, "config file '%s' includes itself", name)
+		}
+	}
+	p.configFiles = append(p.configFiles, key)
+	defer func() { p.configFiles = p.configFiles[:len(p.configFiles)-1] }()
+
+	entries, err := ParseConfig(reader, name, p.Global.messager())
+	var syntaxErr *ConfigSyntaxError
+	if errors.As(err, &syntaxErr) {
+		return &OptionError{Code: ParamReadError, Detail: syntaxErr.Msg, File: name, Line: syntaxErr.Line}
+	}
+	if err != nil {
+		return paramErrorf(ParamReadError, "%s: %v", name, err)
+	}
+	for _, entry := range entries {
+		flag := entry.Option
+		if !strings.HasPrefix(flag, "-") {
+			flag = "--" + flag
+		}
+		if _, err := p.ParseOne(flag, entry.Parameter); err != nil {
+			var oe *OptionError
+			if errors.As(err, &oe) && oe.File == "" {
+				// Errors from a nested config file already have a position.
+				oe.File = entry.File
+				oe.Line = entry.Line
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -1,6 +1,10 @@
 package tool
 
 import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
@@ -16,37 +20,37 @@ func TestParseConfig(t *testing.T) {
 		{
 			name:     "simple option with equals",
 			input:    `user-agent = "my-agent/1.0"`,
-			expected: []ConfigEntry{{Option: "user-agent", Parameter: "my-agent/1.0"}},
+			expected: []ConfigEntry{{Option: "user-agent", Parameter: "my-agent/1.0", Line: 1}},
 		},
 		{
 			name:     "simple option with colon",
 			input:    "output: output.html",
-			expected: []ConfigEntry{{Option: "output", Parameter: "output.html"}},
+			expected: []ConfigEntry{{Option: "output", Parameter: "output.html", Line: 1}},
 		},
 		{
 			name:     "dashed option with space",
 			input:    "--url http://example.com",
-			expected: []ConfigEntry{{Option: "--url", Parameter: "http://example.com"}},
+			expected: []ConfigEntry{{Option: "--url", Parameter: "http://example.com", Line: 1}},
 		},
 		{
 			name:     "short dashed option",
 			input:    "-v",
-			expected: []ConfigEntry{{Option: "-v", Parameter: ""}},
+			expected: []ConfigEntry{{Option: "-v", Parameter: "", Line: 1}},
 		},
 		{
 			name:     "comments and blank lines",
 			input:    "\n# This is a comment\n\nverbose\n",
-			expected: []ConfigEntry{{Option: "verbose", Parameter: ""}},
+			expected: []ConfigEntry{{Option: "verbose", Parameter: "", Line: 4}},
 		},
 		{
 			name:     "quoted parameter with escapes",
 			input:    `data = "hello\tworld\""`,
-			expected: []ConfigEntry{{Option: "data", Parameter: "hello\tworld\""}},
+			expected: []ConfigEntry{{Option: "data", Parameter: "hello\tworld\"", Line: 1}},
 		},
 		{
 			name:     "unquoted parameter with trailing comment",
 			input:    "url = http://example.com # gets the site",
-			expected: []ConfigEntry{{Option: "url", Parameter: "http://example.com"}},
+			expected: []ConfigEntry{{Option: "url", Parameter: "http://example.com", Line: 1}},
 		},
 		{
 			name: "multi-line config",
@@ -64,18 +68,48 @@ user-agent = "Test Agent"
 data = "a \"quoted\" string with a \\ backslash"
 `,
 			expected: []ConfigEntry{
-				{Option: "user-agent", Parameter: "Test Agent"},
-				{Option: "--url", Parameter: "http://localhost/test"},
-				{Option: "--verbose", Parameter: ""},
-				{Option: "data", Parameter: `a "quoted" string with a \ backslash`},
+				{Option: "user-agent", Parameter: "Test Agent", Line: 3},
+				{Option: "--url", Parameter: "http://localhost/test", Line: 6},
+				{Option: "--verbose", Parameter: "", Line: 9},
+				{Option: "data", Parameter: `a "quoted" string with a \ backslash`, Line: 12},
 			},
 		},
+		{
+			name: "url forms",
+			input: "url=\"http://a\"\n" +
+				"url: \"http://b\"\n" +
+				"url \"http://c\"\n" +
+				"--url \"http://d\"\n" +
+				"url=http://e\n",
+			expected: []ConfigEntry{
+				{Option: "url", Parameter: "http://a", Line: 1},
+				{Option: "url", Parameter: "http://b", Line: 2},
+				{Option: "url", Parameter: "http://c", Line: 3},
+				{Option: "--url", Parameter: "http://d", Line: 4},
+				{Option: "url", Parameter: "http://e", Line: 5},
+			},
+		},
+		{
+			name:  "line continuation",
+			input: "user-agent = \"long \\\nagent\"\nverbose\ndata = a\\\\\nsilent\n",
+			expected: []ConfigEntry{
+				{Option: "user-agent", Parameter: "long agent", Line: 1},
+				{Option: "verbose", Parameter: "", Line: 3},
+				{Option: "data", Parameter: `a\\`, Line: 4},
+				{Option: "silent", Parameter: "", Line: 5},
+			},
+		},
+		{
+			name:    "unterminated quote",
+			input:   "verbose\nuser-agent = \"no end\n",
+			wantErr: true,
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			reader := strings.NewReader(tc.input)
-			result, err := ParseConfig(reader)
+			result, err := ParseConfig(reader, "", nil)
 
 			if (err != nil) != tc.wantErr {
 				t.Fatalf("ParseConfig() error = %v, wantErr %v", err, tc.wantErr)
@@ -86,4 +120,133 @@ data = "a \"quoted\" string with a \\ backslash"
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestParseConfig_Diagnostics(t *testing.T) {
+	t.Run("unquoted white space", func(t *testing.T) {
+		var buf bytes.Buffer
+		msg := NewMessager(&buf, false, false, false)
+		input := "user-agent = my agent\nurl = http://a # comment\n"
+		entries, err := ParseConfig(strings.NewReader(input), "test.rc", msg)
+		if err != nil {
+			t.Fatalf("ParseConfig() failed: %v", err)
+		}
+		if len(entries) != 2 || entries[0].Parameter != "my" || entries[0].File != "test.rc" {
+			t.Errorf("entries = %+v", entries)
+		}
+		want := "Warning: test.rc:1: warning: 'user-agent' uses unquoted white space"
+		if !strings.HasPrefix(buf.String(), want) || strings.Count(buf.String(), "test.rc:") != 1 {
+			t.Errorf("warnings = %q; want one starting with %q", buf.String(), want)
+		}
+	})
+
+	t.Run("unterminated quote position", func(t *testing.T) {
+		input := "verbose\n\nuser-agent = \"no end\n"
+		_, err := ParseConfig(strings.NewReader(input), "test.rc", nil)
+		var se *ConfigSyntaxError
+		if !errors.As(err, &se) || se.File != "test.rc" || se.Line != 3 {
+			t.Fatalf("ParseConfig() error = %v; want a syntax error at test.rc:3", err)
+		}
+		if err.Error() != "test.rc:3: unterminated quoted parameter for 'user-agent'" {
+			t.Errorf("Error() = %q", err.Error())
+		}
+	})
+}
+
+func TestParameterParser_ParseConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	t.Run("applies options and nested files", func(t *testing.T) {
+		inner := write("inner.rc", "location\n--max-redirs 7\n")
+		outer := write("outer.rc", "user-agent = \"from-file\"\n-K "+inner+"\nurl = http://example.com\n")
+		global := NewGlobalConfig()
+		if err := NewParameterParser(global).ParseConfigFile(outer); err != nil {
+			t.Fatalf("ParseConfigFile() failed: %v", err)
+		}
+		config := global.Last
+		if config.UserAgent != "from-file" || !config.FollowLocation || config.MaxRedirs != 7 {
+			t.Errorf("UserAgent = %q, FollowLocation = %v, MaxRedirs = %d",
+				config.UserAgent, config.FollowLocation, config.MaxRedirs)
+		}
+		if len(config.URLList) != 1 || config.URLList[0].URL != "http://example.com" {
+			t.Errorf("URLList = %+v", config.URLList)
+		}
+	})
+
+	t.Run("reads stdin", func(t *testing.T) {
+		stdin, err := os.Open(write("stdin.rc", "user-agent = piped\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer stdin.Close()
+		saved := os.Stdin
+		os.Stdin = stdin
+		defer func() { os.Stdin = saved }()
+
+		global := NewGlobalConfig()
+		if err := NewParameterParser(global).Parse([]string{"--config", "-"}); err != nil {
+			t.Fatalf("Parse() failed: %v", err)
+		}
+		if global.Last.UserAgent != "piped" {
+			t.Errorf("UserAgent = %q; want %q", global.Last.UserAgent, "piped")
+		}
+	})
+
+	t.Run("error position", func(t *testing.T) {
+		inner := write("bad.rc", "verbose\n\n# comment\nbogus = 1\n")
+		outer := write("includes-bad.rc", "silent\n--config "+inner+"\n")
+		err := NewParameterParser(NewGlobalConfig()).Parse([]string{"-K", outer})
+		var oe *OptionError
+		if !errors.As(err, &oe) {
+			t.Fatalf("Parse() error = %v; want *OptionError", err)
+		}
+		if oe.Code != ParamOptionUnknown || oe.File != inner || oe.Line != 4 || oe.Option != "--bogus" {
+			t.Errorf("OptionError = %+v", *oe)
+		}
+		want := inner + ":4: option --bogus: is unknown"
+		if err.Error() != want {
+			t.Errorf("Error() = %q; want %q", err.Error(), want)
+		}
+	})
+
+	t.Run("loop", func(t *testing.T) {
+		a := filepath.Join(dir, "a.rc")
+		b := write("b.rc", "config = \""+a+"\"\n")
+		write("a.rc", "verbose\nconfig = \""+b+"\"\n")
+		err := NewParameterParser(NewGlobalConfig()).ParseConfigFile(a)
+		var oe *OptionError
+		if !errors.As(err, &oe) || oe.Code != ParamBadUse || !strings.Contains(oe.Detail, "includes itself") {
+			t.Fatalf("ParseConfigFile() error = %v; want a loop error", err)
+		}
+		if oe.File != b || oe.Line != 1 {
+			t.Errorf("loop reported at %s:%d; want %s:1", oe.File, oe.Line, b)
+		}
+	})
+
+	t.Run("syntax error", func(t *testing.T) {
+		path := write("syntax.rc", "verbose\nurl = \"http://a\n")
+		err := NewParameterParser(NewGlobalConfig()).Parse([]string{"-K", path})
+		var oe *OptionError
+		if !errors.As(err, &oe) || oe.Code != ParamReadError || oe.File != path || oe.Line != 2 {
+			t.Fatalf("Parse() error = %v; want a read error at %s:2", err, path)
+		}
+		if want := path + ":2: error encountered when reading a file"; err.Error() != want {
+			t.Errorf("Error() = %q; want %q", err.Error(), want)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		err := NewParameterParser(NewGlobalConfig()).Parse([]string{"-K", filepath.Join(dir, "nope")})
+		if !errors.Is(err, ParamReadError) {
+			t.Errorf("Parse() error = %v; want %v", err, ParamReadError)
+		}
+	})
+}
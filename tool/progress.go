@@ -60,9 +60,16 @@ func formatSeconds(seconds int64) string {
 	return fmt.Sprintf("%7dd", d)
 }
 
-// TransferStats holds the progress data for a single ongoing transfer.
+// TransferStats holds the progress data for a single transfer. Done marks
+// a transfer that has finished; it still counts towards the totals and the
+// Xfers column, but not towards the Live one. Queued marks transfers not
+// started yet, which only count towards Xfers.
 type TransferStats struct {
 	DLTotal, DLNow, ULTotal, ULNow int64
+	Done, Queued                   bool
+	// Transfers is the number of finished or queued transfers added up in
+	// the stats when they stand for more than one.
+	Transfers int
 }
 
 // ProgressBar renders a command-line progress meter.
@@ -93,11 +100,16 @@ func (p *ProgressBar) Render(stats []TransferStats, final bool) {
 	p.lastRender = now
 
 	var totalDL, totalUL, currentDL, currentUL int64
+	var xfers, live int
 	for _, s := range stats {
+		xfers += max(s.Transfers, 1)
 		totalDL += s.DLTotal
 		totalUL += s.ULTotal
 		currentDL += s.DLNow
 		currentUL += s.ULNow
+		if !s.Done && !s.Queued {
+			live++
+		}
 	}
 
 	dlPercent := "--"
@@ -131,8 +143,8 @@ func (p *ProgressBar) Render(stats []TransferStats, final bool) {
 		ulPercent,
 		formatBytes(currentDL),
 		formatBytes(currentUL),
-		len(stats), // Xfers
-		len(stats), // Live (simplified)
+		xfers,            // Xfers
+		live,             // Live
 		formatSeconds(0), // Total time (simplified)
 		formatSeconds(timeSpent),
 		formatSeconds(timeLeft),
@@ -142,4 +154,4 @@ func (p *ProgressBar) Render(stats []TransferStats, final bool) {
 	if final {
 		fmt.Fprintln(p.Writer)
 	}
-}
\ No newline at end of file
+}
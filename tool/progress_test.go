@@ -94,4 +94,42 @@ func TestProgressBar(t *testing.T) {
 	if !strings.HasSuffix(buf.String(), "\n") {
 		t.Error("Final render should end with a newline")
 	}
-}
\ No newline at end of file
+}
+
+func TestProgressBar_XfersAndLive(t *testing.T) {
+	var buf bytes.Buffer
+	p := NewProgressBar(&buf)
+
+	stats := []TransferStats{
+		{DLTotal: 100, DLNow: 100, Done: true},
+		{DLTotal: 100, DLNow: 10},
+		{DLTotal: 100, DLNow: 20},
+	}
+	p.Render(stats, true)
+
+	// The Xfers column counts every transfer and Live only the running ones.
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(fields) < 6 || fields[4] != "3" || fields[5] != "2" {
+		t.Errorf("Xfers/Live = %v; want 3 and 2 in line %q", fields, lines[len(lines)-1])
+	}
+
+	// Finished transfers added up in one entry count as many.
+	buf.Reset()
+	stats[0].Transfers = 5
+	p.Render(stats, true)
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	fields = strings.Fields(lines[len(lines)-1])
+	if len(fields) < 6 || fields[4] != "7" || fields[5] != "2" {
+		t.Errorf("Xfers/Live = %v; want 7 and 2 in line %q", fields, lines[len(lines)-1])
+	}
+
+	// Queued transfers count towards Xfers but are not live.
+	buf.Reset()
+	p.Render(append(stats, TransferStats{Queued: true, Transfers: 10}), true)
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	fields = strings.Fields(lines[len(lines)-1])
+	if len(fields) < 6 || fields[4] != "17" || fields[5] != "2" {
+		t.Errorf("Xfers/Live = %v; want 17 and 2 in line %q", fields, lines[len(lines)-1])
+	}
+}
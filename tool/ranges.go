@@ -0,0 +1,193 @@
+package tool
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// This file contains the handling of -r/--range beyond the Range header
+// sent over HTTP: the ranges are parsed for the file:// and FTP transfers,
+// which read them from the file themselves, and with --sparse-ranges the
+// parts of a 206 response are written to the output file at their offsets
+// instead of being output one after the other.
+
+// byteRange is one range of bytes of -r, with the offsets of the first and
+// last bytes. An end of -1 means up to the end of the file, and a start of
+// -1 asks for the last End+1 bytes, as "-500" gives the last 500.
+type byteRange struct {
+	Start, End int64
+}
+
+// parseRanges parses a -r argument, one or more ranges such as "0-99",
+// "500-" or "-500" separated with commas.
+func parseRanges(spec string) ([]byteRange, bool) {
+	var ranges []byteRange
+	for _, item := range strings.Split(spec, ",") {
+		from, to, found := strings.Cut(strings.TrimSpace(item), "-")
+		if !found || (from == "" && to == "") {
+			return nil, false
+		}
+		r := byteRange{Start: -1, End: -1}
+		var err error
+		if from != "" {
+			if r.Start, err = strconv.ParseInt(from, 10, 64); err != nil || r.Start < 0 {
+				return nil, false
+			}
+		}
+		if to != "" {
+			if r.End, err = strconv.ParseInt(to, 10, 64); err != nil || r.End < 0 {
+				return nil, false
+			}
+		}
+		switch {
+		case from == "":
+			// The last bytes: End holds the count minus one.
+			if r.End == 0 {
+				return nil, false
+			}
+			r.End--
+		case to != "" && r.End < r.Start:
+			return nil, false
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, true
+}
+
+// resolve returns the range with its offsets within a file of the given
+// size, -1 when the size is not known.
+func (r byteRange) resolve(size int64) (byteRange, *TransferError) {
+	if r.Start < 0 {
+		if size < 0 {
+			return r, newTransferError(CurlRangeError, "Cannot request the end of a file of unknown size")
+		}
+		r.Start = max(size-(r.End+1), 0)
+		r.End = size - 1
+	}
+	if size >= 0 {
+		if r.Start > size {
+			return r, newTransferError(CurlBadDownloadResume, "Offset (%d) was beyond the end of the file (%d)", r.Start, size)
+		}
+		if r.End < 0 || r.End >= size {
+			r.End = size - 1
+		}
+	}
+	return r, nil
+}
+
+// Len returns the number of bytes in the range, -1 if it runs up to the
+// end of a file of unknown size.
+func (r byteRange) Len() int64 {
+	if r.End < 0 {
+		return -1
+	}
+	return r.End - r.Start + 1
+}
+
+// localRanges returns the parts of a file of the given size, -1 when it is
+// not known, that a file:// or FTP transfer reads: the -r ranges, the rest
+// of the file from the -C offset, or the whole file.
+func (t *Transfer) localRanges(size int64) ([]byteRange, *TransferError) {
+	ranges := []byteRange{{Start: t.resumeFrom, End: -1}}
+	if t.Config.Range != "" {
+		var ok bool
+		if ranges, ok = parseRanges(t.Config.Range); !ok {
+			return nil, newTransferError(CurlRangeError, "Bad range specification: %s", t.Config.Range)
+		}
+	}
+	for i, r := range ranges {
+		var terr *TransferError
+		if ranges[i], terr = r.resolve(size); terr != nil {
+			return nil, terr
+		}
+	}
+	return ranges, nil
+}
+
+// rangeWriter returns the writer for a part of the output starting at
+// offset. With --sparse-ranges and an output file, the part is written at
+// its offset in the file; otherwise the parts follow each other.
+func (t *Transfer) rangeWriter(out *transferOutput, offset int64) io.Writer {
+	if t.Config.SparseRanges && out.file != nil {
+		return io.NewOffsetWriter(out.file, offset)
+	}
+	return out.w
+}
+
+// parseContentRange parses a Content-Range header such as
+// "bytes 100-199/200". The total is -1 when the header gives "*".
+func parseContentRange(header string) (start, end, total int64, ok bool) {
+	spec := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(header), "bytes"))
+	from, rest, found := strings.Cut(spec, "-")
+	if !found {
+		return 0, 0, 0, false
+	}
+	to, size, found := strings.Cut(rest, "/")
+	var err1, err2 error
+	start, err1 = strconv.ParseInt(from, 10, 64)
+	end, err2 = strconv.ParseInt(to, 10, 64)
+	if err1 != nil || err2 != nil || !found || end < start {
+		return 0, 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		var err error
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, 0, false
+		}
+	}
+	return start, end, total, true
+}
+
+// writeRangeParts writes the parts of a 206 response at their offsets in
+// the output, for --sparse-ranges. The response is either a single part
+// described by its Content-Range header or, when several ranges were asked
+// for, a multipart/byteranges body. When the size of the whole file is
+// known, the output file is extended to it, leaving holes where no part
+// has been received.
+func (t *Transfer) writeRangeParts(out *transferOutput, resp *http.Response) *TransferError {
+	total := int64(-1)
+	mediaType, params, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "multipart/byteranges" {
+		start, _, size, ok := parseContentRange(resp.Header.Get("Content-Range"))
+		if !ok {
+			return newTransferError(CurlRangeError, "Invalid Content-Range in the response")
+		}
+		if terr := t.copyBody(out, t.rangeWriter(out, start), resp.Body); terr != nil {
+			return terr
+		}
+		total = size
+	} else {
+		mr := multipart.NewReader(resp.Body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return newTransferError(CurlRangeError, "Bad multipart/byteranges response: %v", err)
+			}
+			start, _, size, ok := parseContentRange(part.Header.Get("Content-Range"))
+			if !ok {
+				return newTransferError(CurlRangeError, "Invalid Content-Range in a part of the response")
+			}
+			if terr := t.copyBody(out, t.rangeWriter(out, start), part); terr != nil {
+				return terr
+			}
+			total = size
+		}
+	}
+
+	if out.file != nil && total > 0 {
+		if info, err := out.file.Stat(); err == nil && info.Size() < total {
+			if err := out.file.Truncate(total); err != nil {
+				return newTransferError(CurlWriteError, "Failure writing output to destination")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package tool
+
+import "testing"
+
+func TestParseRanges(t *testing.T) {
+	testCases := []struct {
+		spec string
+		want []byteRange
+	}{
+		{"0-99", []byteRange{{0, 99}}},
+		{"500-", []byteRange{{500, -1}}},
+		{"-500", []byteRange{{-1, 499}}},
+		{"0-0,5-9, 20-", []byteRange{{0, 0}, {5, 9}, {20, -1}}},
+	}
+	for _, tc := range testCases {
+		got, ok := parseRanges(tc.spec)
+		if !ok || len(got) != len(tc.want) {
+			t.Errorf("parseRanges(%q) = %v, %v; want %v", tc.spec, got, ok, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("parseRanges(%q) = %v; want %v", tc.spec, got, tc.want)
+				break
+			}
+		}
+	}
+
+	for _, spec := range []string{"", "-", "abc", "9-5", "-0", "1-2,", "x-1"} {
+		if got, ok := parseRanges(spec); ok {
+			t.Errorf("parseRanges(%q) = %v; want an error", spec, got)
+		}
+	}
+}
+
+func TestByteRange_Resolve(t *testing.T) {
+	testCases := []struct {
+		r    byteRange
+		size int64
+		want byteRange
+		len  int64
+	}{
+		{byteRange{0, 99}, 1000, byteRange{0, 99}, 100},
+		{byteRange{0, 99}, 10, byteRange{0, 9}, 10},
+		{byteRange{500, -1}, 1000, byteRange{500, 999}, 500},
+		{byteRange{500, -1}, -1, byteRange{500, -1}, -1},
+		{byteRange{-1, 99}, 1000, byteRange{900, 999}, 100},
+		{byteRange{-1, 99}, 50, byteRange{0, 49}, 50},
+		{byteRange{10, -1}, 10, byteRange{10, 9}, 0},
+	}
+	for _, tc := range testCases {
+		got, err := tc.r.resolve(tc.size)
+		if err != nil || got != tc.want || got.Len() != tc.len {
+			t.Errorf("%v.resolve(%d) = %v (len %d), %v; want %v (len %d)", tc.r, tc.size, got, got.Len(), err, tc.want, tc.len)
+		}
+	}
+
+	if _, err := (byteRange{20, -1}).resolve(10); err == nil || err.Code != CurlBadDownloadResume {
+		t.Errorf("resolve() beyond the end = %v; want code %d", err, CurlBadDownloadResume)
+	}
+	if _, err := (byteRange{-1, 9}).resolve(-1); err == nil || err.Code != CurlRangeError {
+		t.Errorf("resolve() of a suffix with no size = %v; want code %d", err, CurlRangeError)
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	testCases := []struct {
+		header            string
+		start, end, total int64
+		ok                bool
+	}{
+		{"bytes 0-99/1000", 0, 99, 1000, true},
+		{"bytes 6-10/*", 6, 10, -1, true},
+		{" bytes  6-10/11 ", 6, 10, 11, true},
+		{" 42-50/51", 42, 50, 51, true},
+		{"bytes */1000", 0, 0, 0, false},
+		{"bytes 10-5/20", 0, 0, 0, false},
+		{"bytes 0-5", 0, 0, 0, false},
+		{"", 0, 0, 0, false},
+	}
+	for _, tc := range testCases {
+		start, end, total, ok := parseContentRange(tc.header)
+		if start != tc.start || end != tc.end || total != tc.total || ok != tc.ok {
+			t.Errorf("parseContentRange(%q) = %d, %d, %d, %v; want %d, %d, %d, %v",
+				tc.header, start, end, total, ok, tc.start, tc.end, tc.total, tc.ok)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package tool
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// This file contains the -C/--continue-at support: the offset a transfer
+// resumes at, worked out in the C `single_transfer`, and the checks libcurl
+// makes on the response to a resumed download in lib/http.c.
+
+// resumeOffset returns the offset the transfer resumes at. With "-C -" it
+// is the size of the output file, or 0 when the file does not exist yet or
+// the output goes to stdout.
+func (t *Transfer) resumeOffset() int64 {
+	config := t.Config
+	if !config.UseResume {
+		return 0
+	}
+	if !config.ResumeFromCurrent {
+		return config.ResumeFrom
+	}
+
+	name := t.Outfile
+	if t.UseRemote {
+		// -J cannot be combined with "-C -", so the name comes from the URL.
+		if u, err := url.Parse(t.URL); err == nil {
+			name = Basename(u.Path)
+		}
+	}
+	if name == "" || name == "-" {
+		return 0
+	}
+	info, err := os.Stat(name)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// skipUpload skips the first offset bytes of the upload, size being the
+// size of the whole file or -1 when it is not known.
+func skipUpload(r io.Reader, offset, size int64) *TransferError {
+	if size >= 0 && offset >= size {
+		return newTransferError(CurlPartialFile, "File already completely uploaded")
+	}
+	if seeker, ok := r.(io.Seeker); ok && size >= 0 {
+		if _, err := seeker.Seek(offset, io.SeekStart); err == nil {
+			return nil
+		}
+	}
+	if n, _ := io.CopyN(io.Discard, r, offset); n < offset {
+		return newTransferError(CurlReadError, "Could only read %d bytes from the input", n)
+	}
+	return nil
+}
+
+// checkResume checks the response to a resumed download. The server must
+// send the rest of the file from the offset asked for, unless the file is
+// already complete: then a 416 or a full response of the size already
+// downloaded is accepted, and its body is not output.
+func (t *Transfer) checkResume(resp *http.Response, rangeNotSatisfiable bool) *TransferError {
+	if t.resumeFrom == 0 || t.Infile != "" || resp.Request.Method != http.MethodGet {
+		return nil
+	}
+	if rangeNotSatisfiable {
+		t.ignoreBody = true
+		return nil
+	}
+	if start, _, _, ok := parseContentRange(resp.Header.Get("Content-Range")); ok && start == t.resumeFrom {
+		return nil
+	}
+	if resp.ContentLength == t.resumeFrom {
+		t.trace(InfoTypeText, "The entire document is already downloaded\n")
+		t.ignoreBody = true
+		return nil
+	}
+	return newTransferError(CurlRangeError, "HTTP server does not seem to support byte ranges. Cannot resume.")
+}
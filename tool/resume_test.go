@@ -0,0 +1,25 @@
+package tool
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSkipUpload(t *testing.T) {
+	// A reader that cannot seek is read past the offset.
+	r := io.MultiReader(strings.NewReader("0123456789"))
+	if err := skipUpload(r, 4, -1); err != nil {
+		t.Fatalf("skipUpload() failed: %v", err)
+	}
+	if rest, _ := io.ReadAll(r); string(rest) != "456789" {
+		t.Errorf("rest = %q; want %q", rest, "456789")
+	}
+
+	if err := skipUpload(strings.NewReader("abc"), 5, -1); err == nil || err.Code != CurlReadError {
+		t.Errorf("skipUpload() past the end = %v; want code %d", err, CurlReadError)
+	}
+	if err := skipUpload(strings.NewReader("abc"), 3, 3); err == nil || err.Code != CurlPartialFile {
+		t.Errorf("skipUpload() of a complete upload = %v; want code %d", err, CurlPartialFile)
+	}
+}
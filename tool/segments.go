@@ -0,0 +1,240 @@
+package tool
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// This file contains the segmented downloads of --segments: when the
+// server accepts byte ranges and gives the length of the body, the
+// download is split into segments fetched concurrently with range
+// requests, each written into the output file at its offset. curl has no
+// such mode; it builds on the range and resume handling of the transfer.
+
+// segmentRetries is how many times a failed segment is retried, each time
+// from where it stopped.
+const segmentRetries = 5
+
+// segmentRetryDelay is the pause before a segment is retried.
+var segmentRetryDelay = time.Second
+
+// segment is a part of a segmented download.
+type segment struct {
+	start, end int64 // the offsets of the first and last bytes
+	n          atomic.Int64
+	done       atomic.Bool
+}
+
+// remaining returns the number of bytes of the segment still to fetch.
+func (s *segment) remaining() int64 {
+	return s.end - s.start + 1 - s.n.Load()
+}
+
+// segmentWriter writes the data of a segment at its offset in the output
+// file.
+type segmentWriter struct {
+	t   *Transfer
+	out *transferOutput
+	seg *segment
+	err error // the error of a failed write
+}
+
+func (w *segmentWriter) Write(p []byte) (int, error) {
+	n, err := w.out.file.WriteAt(p, w.seg.start+w.seg.n.Load())
+	w.seg.n.Add(int64(n))
+	w.t.dlNow.Add(int64(n))
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// segmentable reports whether the download of resp can be segmented: it
+// must be asked for with --segments, be a plain GET of a whole body saved
+// to a file, and the server must accept byte ranges and give the length.
+func (t *Transfer) segmentable(resp *http.Response, out *transferOutput) bool {
+	return t.Config.Segments > 1 && out.file != nil && !t.Config.ShowHeaders &&
+		resp.StatusCode == http.StatusOK && resp.Request.Method == http.MethodGet &&
+		resp.ContentLength > 1 && strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes")
+}
+
+// writeSegments downloads the body of resp in segments. The first segment
+// is read from resp itself and the others with range requests, sent over
+// connections of their own so that they are not multiplexed together.
+func (t *Transfer) writeSegments(ctx context.Context, out *transferOutput, resp *http.Response) *TransferError {
+	size := resp.ContentLength
+	count := min(t.Config.Segments, size)
+	if err := out.file.Truncate(size); err != nil {
+		return newTransferError(CurlWriteError, "Failure writing output to destination")
+	}
+
+	segments := make([]*segment, count)
+	for i := range segments {
+		segments[i] = &segment{start: int64(i) * size / count, end: int64(i+1)*size/count - 1}
+	}
+
+	transport, terr := newTransport(t.Config)
+	if terr != nil {
+		return terr
+	}
+	transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	client := &http.Client{Transport: transport}
+	defer transport.CloseIdleConnections()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// The body of resp does not follow ctx: close it when a failure stops
+	// the download.
+	stop := context.AfterFunc(ctx, func() { resp.Body.Close() })
+	defer stop()
+	stopMeter := t.startSegmentMeter(segments)
+	defer stopMeter()
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr *TransferError
+	for i, seg := range segments {
+		var body io.Reader
+		if i == 0 {
+			body = resp.Body
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if terr := t.fetchSegment(ctx, client, resp.Request, out, seg, body); terr != nil {
+				once.Do(func() {
+					firstErr = terr
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, seg := range segments {
+		out.n += seg.n.Load()
+	}
+	t.Info["size_download"] = out.n
+	return firstErr
+}
+
+// fetchSegment downloads seg, reading it first from body if it is set and
+// otherwise with a range request like orig. A failed attempt is retried
+// from the first byte not received yet.
+func (t *Transfer) fetchSegment(ctx context.Context, client *http.Client, orig *http.Request,
+	out *transferOutput, seg *segment, body io.Reader) *TransferError {
+	defer seg.done.Store(true)
+
+	w := &segmentWriter{t: t, out: out, seg: seg}
+	var terr *TransferError
+	for attempt := 0; attempt <= segmentRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(segmentRetryDelay):
+			case <-ctx.Done():
+				return t.classifyError(ctx.Err())
+			}
+		}
+		var retry bool
+		if retry, terr = t.fetchSegmentOnce(ctx, client, orig, w, body); terr == nil || !retry {
+			return terr
+		}
+		body = nil
+	}
+	return terr
+}
+
+// fetchSegmentOnce makes one attempt at downloading the rest of a segment.
+// It tells whether a failure is worth retrying.
+func (t *Transfer) fetchSegmentOnce(ctx context.Context, client *http.Client, orig *http.Request,
+	w *segmentWriter, body io.Reader) (bool, *TransferError) {
+	seg := w.seg
+	if body == nil {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, orig.URL.String(), nil)
+		if err != nil {
+			return false, newTransferError(CurlURLMalformat, "")
+		}
+		req.Header = orig.Header.Clone()
+		from := seg.start + seg.n.Load()
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", from, seg.end))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return ctx.Err() == nil, t.classifyError(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusPartialContent {
+			if resp.StatusCode >= 500 {
+				return true, newTransferError(CurlHTTPReturnedError, "The requested URL returned error: %d", resp.StatusCode)
+			}
+			return false, newTransferError(CurlRangeError, "HTTP server does not seem to support byte ranges. Cannot resume.")
+		}
+		if start, _, _, ok := parseContentRange(resp.Header.Get("Content-Range")); !ok || start != from {
+			return false, newTransferError(CurlRangeError, "HTTP server does not seem to support byte ranges. Cannot resume.")
+		}
+		body = resp.Body
+	}
+
+	_, err := io.Copy(w, io.LimitReader(body, seg.remaining()))
+	switch {
+	case w.err != nil:
+		return false, newTransferError(CurlWriteError, "Failure writing output to destination")
+	case ctx.Err() != nil:
+		return false, t.classifyError(ctx.Err())
+	case err != nil:
+		return true, t.classifyError(err)
+	case seg.remaining() > 0:
+		return true, newTransferError(CurlPartialFile, "transfer closed with %d bytes remaining to read", seg.remaining())
+	}
+	return false, nil
+}
+
+// startSegmentMeter renders the progress of the segments to stderr, one
+// transfer per segment, until the returned function is called. As with the
+// parallel meter, nothing is shown with --silent, nor during a --parallel
+// run, whose meter already counts the download.
+func (t *Transfer) startSegmentMeter(segments []*segment) func() {
+	if t.Global.Silent || t.Global.Parallel {
+		return func() {}
+	}
+	stats := func() []TransferStats {
+		stats := make([]TransferStats, len(segments))
+		for i, seg := range segments {
+			stats[i] = TransferStats{
+				DLTotal: seg.end - seg.start + 1,
+				DLNow:   seg.n.Load(),
+				Done:    seg.done.Load(),
+			}
+		}
+		return stats
+	}
+
+	bar := NewProgressBar(t.Global.Stderr)
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				bar.Render(stats(), false)
+			case <-stop:
+				bar.Render(stats(), true)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(stop)
+		<-finished
+	}
+}
@@ -0,0 +1,88 @@
+package tool
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSegmentedDownload(t *testing.T) {
+	dir := isolateCurlRC(t)
+	oldDelay := segmentRetryDelay
+	segmentRetryDelay = 0
+	t.Cleanup(func() { segmentRetryDelay = oldDelay })
+
+	content := strings.Repeat("0123456789", 100)
+	var mu sync.Mutex
+	var ranges []string
+	failed := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rng := r.Header.Get("Range")
+		mu.Lock()
+		ranges = append(ranges, rng)
+		fail := rng == "bytes=500-749" && !failed && r.URL.Path == "/flaky"
+		if fail {
+			failed = true
+		}
+		mu.Unlock()
+		if fail {
+			// Send part of the segment, then drop the connection.
+			w.Header().Set("Content-Range", "bytes 500-749/1000")
+			w.Header().Set("Content-Length", "250")
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write([]byte(content[500:600]))
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		if r.URL.Path == "/noranges" {
+			w.Write([]byte(content))
+			return
+		}
+		http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+
+	testCases := []struct {
+		path string
+		want []string
+	}{
+		{"/file", []string{"", "bytes=250-499", "bytes=500-749", "bytes=750-999"}},
+		{"/flaky", []string{"", "bytes=250-499", "bytes=500-749", "bytes=600-749", "bytes=750-999"}},
+		{"/noranges", []string{""}},
+	}
+	for _, tc := range testCases {
+		mu.Lock()
+		ranges = nil
+		mu.Unlock()
+		out := filepath.Join(dir, "out.bin")
+		global, _, _ := newTestGlobal()
+		if err := Operate(context.Background(), global, []string{"--segments", "4", "-o", out, srv.URL + tc.path}); err != nil {
+			t.Fatalf("%s: Operate() failed: %v", tc.path, err)
+		}
+		if got, _ := os.ReadFile(out); string(got) != content {
+			t.Errorf("%s: file has %d bytes, not the content", tc.path, len(got))
+		}
+		mu.Lock()
+		sort.Strings(ranges)
+		if strings.Join(ranges, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("%s: ranges = %q; want %q", tc.path, ranges, tc.want)
+		}
+		mu.Unlock()
+	}
+
+	// Without an output file the body is not segmented.
+	global, stdout, _ := newTestGlobal()
+	if err := Operate(context.Background(), global, []string{"--segments", "4", srv.URL + "/file"}); err != nil {
+		t.Fatalf("Operate() failed: %v", err)
+	}
+	if stdout.String() != content {
+		t.Errorf("stdout has %d bytes, not the content", stdout.Len())
+	}
+}
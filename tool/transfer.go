@@ -0,0 +1,786 @@
+package tool
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Transfer holds the state of a single URL transfer. It is the Go equivalent
+// of the C `per_transfer` struct from curl-src/src/tool_operate.h, with the
+// libcurl easy handle replaced by Go's net/http client.
+type Transfer struct {
+	Global *GlobalConfig
+	Config *OperationConfig
+
+	URL string
+	// Outfile is the name of the local file to write the body to. An empty
+	// name or "-" writes to Global.Stdout.
+	Outfile string
+	// UseRemote makes the transfer derive the output file name from the URL
+	// (or from Content-Disposition when Config.ContentDisposition is set).
+	UseRemote bool
+	// Infile is the local file to upload with a PUT, from -T. "-" and "."
+	// upload stdin.
+	Infile string
+
+	// Info holds the --write-out variables collected during the transfer,
+	// keyed by their names in the `variables` map.
+	Info map[string]interface{}
+	// Headers holds the headers of the last response received.
+	Headers http.Header
+
+	start          time.Time
+	host, port     string
+	pendingHeaders []string
+	numRedirects   int64
+	numConnects    int64
+	sizeHeader     int64
+	requestLine    string
+	tracer         *Tracer
+	transport      *http.Transport
+	ownTransport   bool
+	resumeFrom     int64 // the offset the transfer resumes at, from -C
+	ignoreBody     bool  // the body of the response is not output
+
+	// Progress counters, read concurrently by the progress meter.
+	dlNow, dlTotal atomic.Int64
+
+	// traceInfo holds the --write-out variables recorded by the hooks of
+	// clientTrace and CheckRedirect, some of which run on the transport's
+	// goroutines. finishInfo copies them into Info.
+	traceMu   sync.Mutex
+	traceInfo map[string]interface{}
+}
+
+// NewTransfer creates a transfer of rawURL using the settings in config.
+func NewTransfer(global *GlobalConfig, config *OperationConfig, rawURL, outfile string) *Transfer {
+	t := &Transfer{
+		Global:  global,
+		Config:  config,
+		URL:     rawURL,
+		Outfile: outfile,
+		Info:    make(map[string]interface{}),
+	}
+	if global.Verbose {
+		t.tracer = NewTracer(global.Stderr, TracePlain, false)
+	}
+	return t
+}
+
+// defaultPorts are the ports used for URLs that do not give one.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ftp":   "21",
+}
+
+// defaultUserAgent is the User-Agent sent when --user-agent is not used.
+func defaultUserAgent() string {
+	return "curl/" + GetInfo().Version
+}
+
+// Perform runs the transfer. It is the Go counterpart of calling
+// `curl_easy_perform` on the handle prepared by `single_transfer` in
+// curl-src/src/tool_operate.c, followed by the post-transfer work done in
+// `post_per_transfer`. A failure is always reported as a *TransferError.
+func (t *Transfer) Perform(ctx context.Context) error {
+	t.start = time.Now()
+	err := t.perform(ctx)
+	t.finishInfo(err)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (t *Transfer) perform(ctx context.Context) *TransferError {
+	u, err := url.Parse(t.URL)
+	if err == nil && u.Scheme == "" {
+		// Like curl, guess HTTP for URLs given without a scheme.
+		u, err = url.Parse("http://" + t.URL)
+	}
+	if err != nil {
+		return newTransferError(CurlURLMalformat, "")
+	}
+	if u.Scheme == "file" {
+		t.resumeFrom = t.resumeOffset()
+		return t.performFile(u)
+	}
+	if u.Host == "" {
+		return newTransferError(CurlURLMalformat, "")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "ftp" {
+		return newTransferError(CurlUnsupportedProtocol, "Protocol \"%s\" not supported", u.Scheme)
+	}
+	t.host, t.port = u.Hostname(), u.Port()
+	if t.port == "" {
+		t.port = defaultPorts[u.Scheme]
+	}
+
+	if t.Config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Config.Timeout)
+		defer cancel()
+	}
+	if u.Scheme == "ftp" {
+		t.resumeFrom = t.resumeOffset()
+		return t.performFTP(ctx, u)
+	}
+
+	client, terr := t.newClient()
+	if terr != nil {
+		return terr
+	}
+	if t.ownTransport {
+		defer client.CloseIdleConnections()
+	}
+
+	t.resumeFrom = t.resumeOffset()
+	req, terr := t.newRequest(ctx, u)
+	if terr != nil {
+		return terr
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return t.classifyError(err)
+	}
+	defer resp.Body.Close()
+
+	t.collectResponseInfo(resp)
+	t.queueHeaders(resp)
+
+	// A resumed download of a file that is already complete gets a 416,
+	// which is not an error.
+	resumed416 := t.resumeFrom > 0 && t.Infile == "" && resp.StatusCode == http.StatusRequestedRangeNotSatisfiable
+	if t.Config.FailOnError && resp.StatusCode >= 400 && !resumed416 {
+		return newTransferError(CurlHTTPReturnedError, "The requested URL returned error: %d", resp.StatusCode)
+	}
+	if terr := t.checkResume(resp, resumed416); terr != nil {
+		return terr
+	}
+
+	return t.writeBody(ctx, resp)
+}
+
+// classifyError maps an error from the Go networking stack to the code and
+// message libcurl reports for the same failure.
+func (t *Transfer) classifyError(err error) *TransferError {
+	var te *TransferError
+	if errors.As(err, &te) {
+		return te
+	}
+
+	elapsed := time.Since(t.start).Milliseconds()
+	received := t.dlNow.Load()
+
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuth x509.UnknownAuthorityError
+	var hostErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	var recordErr tls.RecordHeaderError
+	var alertErr tls.AlertError
+
+	switch {
+	case errors.As(err, &dnsErr):
+		if t.Config.Proxy != "" {
+			return newTransferError(CurlCouldntResolveProxy, "Could not resolve proxy: %s", dnsErr.Name)
+		}
+		return newTransferError(CurlCouldntResolveHost, "Could not resolve host: %s", dnsErr.Name)
+	case errors.As(err, &opErr) && opErr.Op == "dial":
+		if opErr.Timeout() {
+			return newTransferError(CurlOperationTimedOut, "Connection timed out after %d milliseconds", elapsed)
+		}
+		return newTransferError(CurlCouldntConnect, "Failed to connect to %s port %s after %d ms: %s",
+			t.host, t.port, elapsed, CurlCouldntConnect)
+	case errors.Is(err, context.DeadlineExceeded), isTimeout(err):
+		return newTransferError(CurlOperationTimedOut, "Operation timed out after %d milliseconds with %d bytes received",
+			elapsed, received)
+	case errors.As(err, &certErr), errors.As(err, &unknownAuth), errors.As(err, &hostErr), errors.As(err, &invalidErr):
+		return newTransferError(CurlPeerFailedVerification, "SSL certificate problem: %v", err)
+	case errors.As(err, &recordErr), errors.As(err, &alertErr):
+		return newTransferError(CurlSSLConnectError, "TLS connect error: %v", err)
+	case errors.Is(err, io.EOF):
+		return newTransferError(CurlGotNothing, "Empty reply from server")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return newTransferError(CurlPartialFile, "transfer closed with outstanding read data remaining")
+	case errors.Is(err, context.Canceled):
+		return newTransferError(CurlAbortedByCallback, "")
+	default:
+		return newTransferError(CurlRecvError, "%v", err)
+	}
+}
+
+// isTimeout reports whether err is a network timeout.
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// newTransport builds the HTTP transport for the options in config.
+// Transfers sharing a transport can reuse each other's connections, and
+// multiplex over them when HTTP/2 is used.
+func newTransport(config *OperationConfig) (*http.Transport, *TransferError) {
+	dialer := &net.Dialer{Timeout: config.ConnectTimeout}
+	transport := &http.Transport{
+		Proxy:              http.ProxyFromEnvironment,
+		DialContext:        dialer.DialContext,
+		TLSClientConfig:    &tls.Config{InsecureSkipVerify: config.InsecureOK},
+		ForceAttemptHTTP2:  true,
+		DisableCompression: true,
+	}
+	if config.Proxy != "" {
+		proxy := config.Proxy
+		if !strings.Contains(proxy, "://") {
+			proxy = "http://" + proxy
+		}
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return nil, newTransferError(CurlCouldntResolveProxy, "Could not resolve proxy: %s", config.Proxy)
+		}
+		if config.ProxyUserPassword != "" {
+			user, pass, _ := strings.Cut(config.ProxyUserPassword, ":")
+			proxyURL.User = url.UserPassword(user, pass)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return transport, nil
+}
+
+// newClient builds the HTTP client for the transfer, using the shared
+// transport if one was given and a private one otherwise.
+func (t *Transfer) newClient() (*http.Client, *TransferError) {
+	config := t.Config
+
+	transport := t.transport
+	if transport == nil {
+		var terr *TransferError
+		if transport, terr = newTransport(config); terr != nil {
+			return nil, terr
+		}
+		t.transport = transport
+		t.ownTransport = true
+	}
+
+	client := &http.Client{Transport: transport}
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !config.FollowLocation {
+			return http.ErrUseLastResponse
+		}
+		if config.MaxRedirs >= 0 && int64(len(via)) > config.MaxRedirs {
+			return newTransferError(CurlTooManyRedirects, "Maximum (%d) redirects followed", config.MaxRedirs)
+		}
+		t.numRedirects++
+		t.setTraceInfo("time_redirect", time.Since(t.start).Seconds())
+		t.requestLine = requestLine(req)
+		if req.Response != nil {
+			t.queueHeaders(req.Response)
+		}
+		return nil
+	}
+	return client, nil
+}
+
+// newRequest builds the HTTP request, applying the method, body and
+// headers selected on the command line.
+func (t *Transfer) newRequest(ctx context.Context, u *url.URL) (*http.Request, *TransferError) {
+	config := t.Config
+
+	method := http.MethodGet
+	var body io.Reader
+	if config.NoBody {
+		method = http.MethodHead
+	} else if config.HTTPReq.Get() == HTTPRequestSimplePost && !config.UseHTTPGet {
+		method = http.MethodPost
+		body = strings.NewReader(config.PostFields)
+	}
+	contentLength := int64(-1)
+	if t.Infile != "" {
+		file, size, err := openUpload(ctx, t.Infile)
+		if err != nil {
+			t.Global.messager().Warnf("Can't open '%s'", t.Infile)
+			return nil, newTransferError(CurlReadError, "")
+		}
+		if t.resumeFrom > 0 {
+			if terr := skipUpload(file, t.resumeFrom, size); terr != nil {
+				file.Close()
+				return nil, terr
+			}
+		}
+		method = http.MethodPut
+		body = file
+		contentLength = size
+	}
+	var form *formBody
+	if config.HTTPReq.Get() == HTTPRequestMimePost {
+		var terr *TransferError
+		if form, terr = newFormBody(config.Form, config.FormEscape); terr != nil {
+			return nil, terr
+		}
+		method = http.MethodPost
+		body = form.Reader()
+	}
+	if config.CustomRequest != "" {
+		method = config.CustomRequest
+	}
+
+	ctx = httptrace.WithClientTrace(ctx, t.clientTrace())
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, newTransferError(CurlURLMalformat, "")
+	}
+
+	req.Header.Set("User-Agent", defaultUserAgent())
+	req.Header.Set("Accept", "*/*")
+	if config.JSON {
+		req.Header.Set("Accept", "application/json")
+	}
+	if config.UserAgent != "" {
+		req.Header.Set("User-Agent", config.UserAgent)
+	}
+	if config.Referer != "" {
+		req.Header.Set("Referer", config.Referer)
+	}
+	if form != nil {
+		req.ContentLength = form.Size
+		req.Header.Set("Content-Type", form.ContentType)
+	} else if t.Infile != "" {
+		// An upload of unknown size is sent chunked.
+		req.ContentLength = contentLength
+		if t.resumeFrom > 0 && contentLength >= 0 {
+			req.ContentLength = contentLength - t.resumeFrom
+			req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", t.resumeFrom, contentLength-1, contentLength))
+		}
+	} else if body != nil {
+		if config.JSON {
+			req.Header.Set("Content-Type", "application/json")
+		} else {
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		}
+	}
+	if t.Infile == "" {
+		if t.resumeFrom > 0 {
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", t.resumeFrom))
+		} else if config.Range != "" {
+			req.Header.Set("Range", "bytes="+config.Range)
+		}
+	}
+	if config.UserPassword != "" {
+		user, pass, _ := strings.Cut(config.UserPassword, ":")
+		req.SetBasicAuth(user, pass)
+	}
+	applyCustomHeaders(req, config.Headers)
+	t.requestLine = requestLine(req)
+	return req, nil
+}
+
+// requestLine returns the first line of an HTTP/1.1 request for the
+// --verbose trace.
+func requestLine(req *http.Request) string {
+	return fmt.Sprintf("%s %s HTTP/1.1\n", req.Method, req.URL.RequestURI())
+}
+
+// applyCustomHeaders adds the -H headers to the request. As in curl, a
+// header replaces any internal header of the same name, "Name:" removes an
+// internal header and "Name;" sends the header with an empty value.
+func applyCustomHeaders(req *http.Request, headers []string) {
+	replaced := make(map[string]bool)
+	for _, h := range headers {
+		name, value, found := strings.Cut(h, ":")
+		if !found {
+			if n, ok := strings.CutSuffix(h, ";"); ok {
+				req.Header[http.CanonicalHeaderKey(strings.TrimSpace(n))] = []string{""}
+			}
+			continue
+		}
+		name = http.CanonicalHeaderKey(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if value == "" {
+			// An empty User-Agent is the way to tell net/http not to send one.
+			if name == "User-Agent" {
+				req.Header.Set(name, "")
+			} else {
+				req.Header.Del(name)
+			}
+			continue
+		}
+		if name == "Host" {
+			req.Host = value
+			continue
+		}
+		if !replaced[name] {
+			req.Header.Del(name)
+			replaced[name] = true
+		}
+		req.Header.Add(name, value)
+	}
+}
+
+// clientTrace returns the hooks used to collect the timing and connection
+// details reported by --write-out, and to print the --verbose trace.
+func (t *Transfer) clientTrace() *httptrace.ClientTrace {
+	since := func() float64 { return time.Since(t.start).Seconds() }
+	return &httptrace.ClientTrace{
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.setTraceInfo("time_namelookup", since())
+		},
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				t.setTraceInfo("time_connect", since())
+			}
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.setTraceInfo("time_appconnect", since())
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if !info.Reused {
+				t.numConnects++
+			}
+			t.setTraceInfo("time_pretransfer", since())
+			if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok {
+				t.setTraceInfo("remote_ip", addr.IP.String())
+				t.setTraceInfo("remote_port", int64(addr.Port))
+				t.trace(InfoTypeText, fmt.Sprintf("Connected to %s port %d\n", addr.IP, addr.Port))
+			}
+			t.trace(InfoTypeHeaderOut, t.requestLine)
+			if addr, ok := info.Conn.LocalAddr().(*net.TCPAddr); ok {
+				t.setTraceInfo("local_ip", addr.IP.String())
+				t.setTraceInfo("local_port", int64(addr.Port))
+			}
+		},
+		WroteHeaderField: func(key string, value []string) {
+			for _, v := range value {
+				t.trace(InfoTypeHeaderOut, key+": "+v+"\n")
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.setTraceInfo("time_posttransfer", since())
+		},
+		GotFirstResponseByte: func() {
+			t.setTraceInfo("time_starttransfer", since())
+		},
+	}
+}
+
+// setTraceInfo records the --write-out variable name for finishInfo. It is
+// safe to call from the transport's goroutines.
+func (t *Transfer) setTraceInfo(name string, value interface{}) {
+	t.traceMu.Lock()
+	defer t.traceMu.Unlock()
+	if t.traceInfo == nil {
+		t.traceInfo = make(map[string]interface{})
+	}
+	t.traceInfo[name] = value
+}
+
+// trace passes a message to the --verbose tracer, if any.
+func (t *Transfer) trace(infoType InfoType, text string) {
+	if t.tracer != nil {
+		t.tracer.Trace(infoType, []byte(text))
+	}
+}
+
+// headerLines renders a response header block the way it came off the
+// wire, one CRLF-terminated line per entry, ending with the blank line.
+func headerLines(resp *http.Response) []string {
+	var status string
+	if resp.ProtoMajor >= 2 {
+		status = fmt.Sprintf("HTTP/%d %d \r\n", resp.ProtoMajor, resp.StatusCode)
+	} else {
+		status = fmt.Sprintf("%s %s\r\n", resp.Proto, resp.Status)
+	}
+	lines := []string{status}
+
+	names := make([]string, 0, len(resp.Header))
+	for name := range resp.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, v := range resp.Header[name] {
+			lines = append(lines, name+": "+v+"\r\n")
+		}
+	}
+	return append(lines, "\r\n")
+}
+
+// queueHeaders records the header block of a response so it can be shown
+// with --include once the output stream is known. Redirect responses are
+// queued as they pass by, giving the same output as curl with -iL.
+func (t *Transfer) queueHeaders(resp *http.Response) {
+	for _, line := range headerLines(resp) {
+		t.pendingHeaders = append(t.pendingHeaders, line)
+		t.sizeHeader += int64(len(line))
+		t.trace(InfoTypeHeaderIn, line)
+	}
+}
+
+// collectResponseInfo records the --write-out variables describing the
+// final response.
+func (t *Transfer) collectResponseInfo(resp *http.Response) {
+	t.Headers = resp.Header
+	t.Info["http_code"] = int64(resp.StatusCode)
+	t.Info["response_code"] = int64(resp.StatusCode)
+	t.Info["content_type"] = resp.Header.Get("Content-Type")
+	t.Info["url_effective"] = resp.Request.URL.String()
+	t.Info["method"] = resp.Request.Method
+	t.Info["scheme"] = resp.Request.URL.Scheme
+	if resp.ProtoMajor >= 2 {
+		t.Info["http_version"] = strconv.Itoa(resp.ProtoMajor)
+	} else {
+		t.Info["http_version"] = fmt.Sprintf("%d.%d", resp.ProtoMajor, resp.ProtoMinor)
+	}
+	if ref := resp.Request.Header.Get("Referer"); ref != "" {
+		t.Info["referer"] = ref
+	}
+	if loc, err := resp.Location(); err == nil && resp.StatusCode/100 == 3 {
+		t.Info["redirect_url"] = loc.String()
+	}
+}
+
+// finishInfo fills in the totals and the result once the transfer has ended.
+func (t *Transfer) finishInfo(err *TransferError) {
+	t.traceMu.Lock()
+	for name, value := range t.traceInfo {
+		t.Info[name] = value
+	}
+	t.traceMu.Unlock()
+	total := time.Since(t.start).Seconds()
+	t.Info["time_total"] = total
+	t.Info["num_redirects"] = t.numRedirects
+	t.Info["num_connects"] = t.numConnects
+	t.Info["size_header"] = t.sizeHeader
+	if size, ok := t.Info["size_download"].(int64); ok && total > 0 {
+		t.Info["speed_download"] = int64(float64(size) / total)
+	}
+	if _, ok := t.Info["url_effective"]; !ok {
+		t.Info["url_effective"] = t.URL
+	}
+	t.Info["exitcode"] = int64(CurlOK)
+	if err != nil {
+		t.Info["exitcode"] = int64(err.Code)
+		t.Info["errormsg"] = err.Message
+	}
+}
+
+// outputName works out the name of the local file the body is saved to,
+// or "" for stdout. remotePath is the path of the URL, and disposition the
+// file name from a Content-Disposition header, if any.
+func (t *Transfer) outputName(remotePath, disposition string) (string, *TransferError) {
+	if !t.UseRemote {
+		if t.Outfile == "-" {
+			return "", nil
+		}
+		return t.Outfile, nil
+	}
+	if disposition != "" {
+		// Never let the server pick a directory.
+		return Basename(disposition), nil
+	}
+	name := Basename(remotePath)
+	if name == "" {
+		return "", newTransferError(CurlWriteError, "Remote file name has no length")
+	}
+	return name, nil
+}
+
+// transferOutput is where the body of a transfer is written.
+type transferOutput struct {
+	w    io.Writer
+	file *os.File // the output file, nil for stdout
+	name string
+	tty  struct{ IsTTY, TerminalBinaryOK bool }
+	n    int64 // the number of body bytes written
+}
+
+// openOutput opens the output of the transfer: the file called name, or
+// stdout when name is "". fromDisposition tells that the server picked the
+// name, and such files are not overwritten. A resumed transfer appends to
+// the file, and one with --sparse-ranges keeps its content, as the parts
+// received are written at their offsets.
+func (t *Transfer) openOutput(name string, fromDisposition bool) (*transferOutput, *TransferError) {
+	out := &transferOutput{w: t.Global.Stdout}
+	out.tty.TerminalBinaryOK = t.Outfile == "-"
+	if name == "" {
+		if f, ok := out.w.(*os.File); ok {
+			out.tty.IsTTY = term.IsTerminal(int(f.Fd()))
+		}
+		return out, nil
+	}
+
+	var file *os.File
+	var err error
+	switch {
+	case t.Config.SparseRanges && t.resumeFrom == 0:
+		file, err = os.OpenFile(name, os.O_WRONLY|os.O_CREATE, 0644)
+	case t.resumeFrom > 0:
+		file, name, err = CreateOutputFile(name, ClobberAppend, fromDisposition)
+	default:
+		file, name, err = CreateOutputFile(name, ClobberDefault, fromDisposition)
+	}
+	if err != nil {
+		t.Global.messager().Warnf("Failed to open the file %s: %v", name, err)
+		return nil, newTransferError(CurlWriteError, "Failure writing output to destination")
+	}
+	out.w, out.file, out.name = file, file, name
+	t.Info["filename_effective"] = name
+	return out, nil
+}
+
+// Close closes the output file, if any.
+func (o *transferOutput) Close() {
+	if o.file != nil {
+		o.file.Close()
+	}
+}
+
+// copyBody copies the body data read from r to dst, the output or a part
+// of it.
+func (t *Transfer) copyBody(out *transferOutput, dst io.Writer, r io.Reader) *TransferError {
+	bw := &bodyWriter{w: dst, tty: out.tty, progress: &t.dlNow}
+	_, err := io.Copy(bw, r)
+	out.n += bw.n
+	t.Info["size_download"] = out.n
+	if bw.err != nil {
+		if bw.binary {
+			t.Global.messager().Warnf("Binary output can mess up your terminal. Use \"--output -\" to tell " +
+				"curl to output it to your terminal anyway, or consider \"--output <FILE>\" to save to a file.")
+		}
+		return newTransferError(CurlWriteError, "Failure writing output to destination")
+	}
+	if err != nil {
+		return t.classifyError(err)
+	}
+	return nil
+}
+
+// writeBody processes the response headers and writes the response body to
+// the output, creating the output file when needed. ctx is the context of
+// the transfer, for the requests of a segmented download.
+func (t *Transfer) writeBody(ctx context.Context, resp *http.Response) *TransferError {
+	hp := NewHeaderProcessor()
+	hp.HonorContentDisposition = t.UseRemote && t.Config.ContentDisposition
+	if t.Config.HeaderFile != "" {
+		w, closeFn, err := openHeaderFile(t.Global, t.Config.HeaderFile)
+		if err != nil {
+			t.Global.messager().Warnf("Failed to open %s", t.Config.HeaderFile)
+			return newTransferError(CurlWriteError, "Failure writing output to destination")
+		}
+		defer closeFn()
+		hp.HeaderWriters = append(hp.HeaderWriters, w)
+	}
+	for _, line := range t.pendingHeaders {
+		if err := hp.Process(line); err != nil {
+			return newTransferError(CurlWriteError, "Failed writing header")
+		}
+	}
+
+	name, terr := t.outputName(resp.Request.URL.Path, hp.FilenameFromDisposition)
+	if terr != nil {
+		return terr
+	}
+	out, terr := t.openOutput(name, hp.FilenameFromDisposition != "")
+	if terr != nil {
+		return terr
+	}
+	defer out.Close()
+
+	if t.Config.ShowHeaders {
+		for _, line := range t.pendingHeaders {
+			if _, err := io.WriteString(out.w, line); err != nil {
+				return newTransferError(CurlWriteError, "Failed writing header")
+			}
+		}
+	}
+
+	t.Info["size_download"] = int64(0)
+	if t.ignoreBody {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	t.dlTotal.Store(resp.ContentLength)
+	switch {
+	case t.segmentable(resp, out):
+		terr = t.writeSegments(ctx, out, resp)
+	case t.Config.SparseRanges && resp.StatusCode == http.StatusPartialContent:
+		terr = t.writeRangeParts(out, resp)
+	default:
+		terr = t.copyBody(out, out.w, resp.Body)
+	}
+	if terr != nil {
+		return terr
+	}
+
+	if t.Config.RemoteTime && out.file != nil {
+		if modTime, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+			SetFileTime(modTime.Unix(), out.name)
+		}
+	}
+	return nil
+}
+
+// openHeaderFile opens the --dump-header target, with "-" meaning stdout.
+func openHeaderFile(global *GlobalConfig, name string) (io.Writer, func(), error) {
+	if name == "-" {
+		return global.Stdout, func() {}, nil
+	}
+	file, err := os.Create(name)
+	if err != nil {
+		return nil, nil, fmt.Errorf("Failed to open %s: %v", name, err)
+	}
+	return file, func() { file.Close() }, nil
+}
+
+// bodyWriter adapts WriteCallback to an io.Writer so the response body can
+// be streamed with io.Copy. It counts the bytes written and remembers a
+// failure reported by the callback.
+type bodyWriter struct {
+	w        io.Writer
+	tty      struct{ IsTTY, TerminalBinaryOK bool }
+	n        int64
+	progress *atomic.Int64
+	err      error
+	binary   bool
+}
+
+func (b *bodyWriter) Write(p []byte) (int, error) {
+	n, err := WriteCallback(b.w, p, &b.tty)
+	b.n += int64(n)
+	if b.progress != nil {
+		b.progress.Add(int64(n))
+	}
+	if err != nil {
+		b.err = err
+		b.binary = n == 0 && b.tty.IsTTY && !b.tty.TerminalBinaryOK
+	}
+	return n, err
+}
+
+// Stats returns a snapshot of the transfer's progress for the progress
+// meter. It is safe to call while the transfer is running.
+func (t *Transfer) Stats() TransferStats {
+	total := t.dlTotal.Load()
+	if total < 0 {
+		total = 0
+	}
+	return TransferStats{DLTotal: total, DLNow: t.dlNow.Load()}
+}
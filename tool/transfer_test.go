@@ -0,0 +1,392 @@
+package tool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestServer starts an HTTP server with a few endpoints used by the
+// transfer tests.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/hello", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		fmt.Fprint(w, "hello world")
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "%s %s ua=%s x=%s", r.Method, r.URL.RequestURI(), r.UserAgent(), r.Header.Get("X-Test"))
+	})
+	mux.HandleFunc("/body", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s type=%s accept=%s body=%s", r.Method, r.Header.Get("Content-Type"), r.Header.Get("Accept"), body)
+	})
+	mux.HandleFunc("/upload/", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		fmt.Fprintf(w, "%s %s length=%d chunked=%v", r.Method, r.URL.Path, r.ContentLength,
+			len(r.TransferEncoding) > 0 && r.TransferEncoding[0] == "chunked")
+		if cr := r.Header.Get("Content-Range"); cr != "" {
+			fmt.Fprintf(w, " range=%s", cr)
+		}
+		fmt.Fprintf(w, " body=%s\n", body)
+	})
+	mux.HandleFunc("/ranged", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeContent(w, r, "ranged", time.Time{}, strings.NewReader("hello world"))
+	})
+	mux.HandleFunc("/form", func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			content, _ := io.ReadAll(part)
+			fmt.Fprintf(w, "%s=%s", part.FormName(), content)
+			if part.FileName() != "" {
+				fmt.Fprintf(w, " (%s)", part.FileName())
+			}
+			fmt.Fprintln(w)
+		}
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/hello", http.StatusFound)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not here", http.StatusNotFound)
+	})
+	mux.HandleFunc("/attachment", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="../served.txt"`)
+		fmt.Fprint(w, "attached")
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestGlobal returns a GlobalConfig that writes to buffers.
+func newTestGlobal() (*GlobalConfig, *bytes.Buffer, *bytes.Buffer) {
+	var stdout, stderr bytes.Buffer
+	global := NewGlobalConfig()
+	global.Stdout = &stdout
+	global.Stderr = &stderr
+	return global, &stdout, &stderr
+}
+
+func TestTransfer_Perform(t *testing.T) {
+	srv := newTestServer(t)
+
+	t.Run("body to stdout", func(t *testing.T) {
+		global, stdout, _ := newTestGlobal()
+		tr := NewTransfer(global, global.Last, srv.URL+"/hello", "")
+		if err := tr.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		if stdout.String() != "hello world" {
+			t.Errorf("stdout = %q; want %q", stdout.String(), "hello world")
+		}
+		if tr.Info["http_code"] != int64(200) {
+			t.Errorf("http_code = %v; want 200", tr.Info["http_code"])
+		}
+		if tr.Info["size_download"] != int64(11) {
+			t.Errorf("size_download = %v; want 11", tr.Info["size_download"])
+		}
+		if tr.Info["content_type"] != "text/plain" {
+			t.Errorf("content_type = %v; want text/plain", tr.Info["content_type"])
+		}
+	})
+
+	t.Run("trace timings of an upload", func(t *testing.T) {
+		// The trace hooks run on the transport's goroutines: with -race,
+		// this catches them writing the transfer's state unguarded.
+		upload := filepath.Join(t.TempDir(), "upload.txt")
+		if err := os.WriteFile(upload, []byte(strings.Repeat("x", 1<<16)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		global, _, _ := newTestGlobal()
+		tr := NewTransfer(global, global.Last, srv.URL+"/upload/", "")
+		tr.Infile = upload
+		if err := tr.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		total, _ := tr.Info["time_total"].(float64)
+		for _, name := range []string{"time_connect", "time_pretransfer", "time_posttransfer", "time_starttransfer"} {
+			if v, ok := tr.Info[name].(float64); !ok || v > total {
+				t.Errorf("%s = %v; want a time up to time_total %v", name, tr.Info[name], total)
+			}
+		}
+		if tr.Info["remote_ip"] != "127.0.0.1" {
+			t.Errorf("remote_ip = %v; want 127.0.0.1", tr.Info["remote_ip"])
+		}
+	})
+
+	t.Run("body to file", func(t *testing.T) {
+		global, stdout, _ := newTestGlobal()
+		out := filepath.Join(t.TempDir(), "out.txt")
+		tr := NewTransfer(global, global.Last, srv.URL+"/hello", out)
+		if err := tr.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		content, err := os.ReadFile(out)
+		if err != nil || string(content) != "hello world" {
+			t.Errorf("file content = %q, %v; want %q", content, err, "hello world")
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("stdout should be empty, got %q", stdout.String())
+		}
+		if tr.Info["filename_effective"] != out {
+			t.Errorf("filename_effective = %v; want %q", tr.Info["filename_effective"], out)
+		}
+	})
+
+	t.Run("request options", func(t *testing.T) {
+		global, stdout, _ := newTestGlobal()
+		config := global.Last
+		config.UserAgent = "agent/1"
+		config.Headers = []string{"X-Test: yes"}
+		config.PostFields = "a=b"
+		config.HTTPReq.Set(HTTPRequestSimplePost)
+		tr := NewTransfer(global, config, srv.URL+"/echo?q=1", "")
+		if err := tr.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		want := "POST /echo?q=1 ua=agent/1 x=yes"
+		if stdout.String() != want {
+			t.Errorf("stdout = %q; want %q", stdout.String(), want)
+		}
+	})
+
+	t.Run("include headers", func(t *testing.T) {
+		global, stdout, _ := newTestGlobal()
+		global.Last.ShowHeaders = true
+		tr := NewTransfer(global, global.Last, srv.URL+"/hello", "")
+		if err := tr.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		out := stdout.String()
+		if !strings.HasPrefix(out, "HTTP/1.1 200 OK\r\n") || !strings.HasSuffix(out, "\r\n\r\nhello world") {
+			t.Errorf("unexpected output with --include: %q", out)
+		}
+	})
+
+	t.Run("redirects", func(t *testing.T) {
+		global, stdout, _ := newTestGlobal()
+		tr := NewTransfer(global, global.Last, srv.URL+"/redirect", "")
+		if err := tr.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		if tr.Info["http_code"] != int64(302) || tr.Info["redirect_url"] != srv.URL+"/hello" {
+			t.Errorf("without -L: http_code = %v, redirect_url = %v", tr.Info["http_code"], tr.Info["redirect_url"])
+		}
+
+		stdout.Reset()
+		global.Last.FollowLocation = true
+		tr = NewTransfer(global, global.Last, srv.URL+"/redirect", "")
+		if err := tr.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		if stdout.String() != "hello world" || tr.Info["num_redirects"] != int64(1) {
+			t.Errorf("with -L: stdout = %q, num_redirects = %v", stdout.String(), tr.Info["num_redirects"])
+		}
+	})
+
+	t.Run("fail on error", func(t *testing.T) {
+		global, stdout, _ := newTestGlobal()
+		global.Last.FailOnError = true
+		tr := NewTransfer(global, global.Last, srv.URL+"/missing", "")
+		err := tr.Perform(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "returned error: 404") {
+			t.Errorf("Perform() error = %v; want HTTP 404 error", err)
+		}
+		if stdout.Len() != 0 {
+			t.Errorf("body should not be written with --fail, got %q", stdout.String())
+		}
+	})
+
+	t.Run("remote name and content disposition", func(t *testing.T) {
+		dir := t.TempDir()
+		wd, _ := os.Getwd()
+		if err := os.Chdir(dir); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Chdir(wd)
+
+		global, _, _ := newTestGlobal()
+		tr := NewTransfer(global, global.Last, srv.URL+"/hello", "")
+		tr.UseRemote = true
+		if err := tr.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		if content, err := os.ReadFile(filepath.Join(dir, "hello")); err != nil || string(content) != "hello world" {
+			t.Errorf("remote name file = %q, %v", content, err)
+		}
+
+		global.Last.ContentDisposition = true
+		tr = NewTransfer(global, global.Last, srv.URL+"/attachment", "")
+		tr.UseRemote = true
+		if err := tr.Perform(context.Background()); err != nil {
+			t.Fatalf("Perform() failed: %v", err)
+		}
+		if content, err := os.ReadFile(filepath.Join(dir, "served.txt")); err != nil || string(content) != "attached" {
+			t.Errorf("content disposition file = %q, %v", content, err)
+		}
+	})
+}
+
+func TestTransfer_ErrorCodes(t *testing.T) {
+	srv := newTestServer(t)
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer slow.Close()
+
+	loop := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, r.URL.Path, http.StatusFound)
+	}))
+	defer loop.Close()
+
+	hangup := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, _ := w.(http.Hijacker).Hijack()
+		conn.Close()
+	}))
+	defer hangup.Close()
+
+	// Find a local port nobody listens on.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closedURL := "http://" + ln.Addr().String() + "/"
+	ln.Close()
+
+	testCases := []struct {
+		name     string
+		url      string
+		setup    func(config *OperationConfig)
+		wantCode CurlCode
+		wantMsg  string
+	}{
+		{
+			name:     "unsupported protocol",
+			url:      "gopher://example.com/",
+			wantCode: CurlUnsupportedProtocol,
+			wantMsg:  `(1) Protocol "gopher" not supported`,
+		},
+		{
+			name:     "malformed URL",
+			url:      "http://",
+			wantCode: CurlURLMalformat,
+			wantMsg:  "(3) URL using bad/illegal format or missing URL",
+		},
+		{
+			name:     "connection refused",
+			url:      closedURL,
+			wantCode: CurlCouldntConnect,
+			wantMsg:  "(7) Failed to connect to 127.0.0.1 port",
+		},
+		{
+			name:     "HTTP error with --fail",
+			url:      srv.URL + "/missing",
+			setup:    func(config *OperationConfig) { config.FailOnError = true },
+			wantCode: CurlHTTPReturnedError,
+			wantMsg:  "(22) The requested URL returned error: 404",
+		},
+		{
+			name:     "max time",
+			url:      slow.URL,
+			setup:    func(config *OperationConfig) { config.Timeout = 100 * time.Millisecond },
+			wantCode: CurlOperationTimedOut,
+			wantMsg:  "(28) Operation timed out after",
+		},
+		{
+			name: "too many redirects",
+			url:  loop.URL + "/loop",
+			setup: func(config *OperationConfig) {
+				config.FollowLocation = true
+				config.MaxRedirs = 3
+			},
+			wantCode: CurlTooManyRedirects,
+			wantMsg:  "(47) Maximum (3) redirects followed",
+		},
+		{
+			name:     "empty reply",
+			url:      hangup.URL,
+			wantCode: CurlGotNothing,
+			wantMsg:  "(52) Empty reply from server",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			global, _, _ := newTestGlobal()
+			if tc.setup != nil {
+				tc.setup(global.Last)
+			}
+			tr := NewTransfer(global, global.Last, tc.url, "")
+			err := tr.Perform(context.Background())
+
+			var te *TransferError
+			if !errors.As(err, &te) {
+				t.Fatalf("Perform() error = %v; want a *TransferError", err)
+			}
+			if te.Code != tc.wantCode {
+				t.Errorf("Code = %d; want %d (error %q)", te.Code, tc.wantCode, err)
+			}
+			if !strings.HasPrefix(err.Error(), tc.wantMsg) {
+				t.Errorf("Error() = %q; want prefix %q", err, tc.wantMsg)
+			}
+			if tr.Info["exitcode"] != int64(tc.wantCode) {
+				t.Errorf("exitcode info = %v; want %d", tr.Info["exitcode"], tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestApplyCustomHeaders(t *testing.T) {
+	req, _ := http.NewRequest("GET", "http://example.com/", nil)
+	req.Header.Set("Accept", "*/*")
+	req.Header.Set("User-Agent", "curl")
+
+	applyCustomHeaders(req, []string{
+		"Accept: text/html",
+		"User-Agent:",
+		"X-Empty;",
+		"X-Multi: 1",
+		"X-Multi: 2",
+		"Host: other.example",
+	})
+
+	if got := req.Header.Get("Accept"); got != "text/html" {
+		t.Errorf("Accept = %q; want text/html", got)
+	}
+	if v, ok := req.Header["User-Agent"]; !ok || v[0] != "" {
+		t.Errorf("User-Agent should be present and empty, got %v", v)
+	}
+	if v, ok := req.Header["X-Empty"]; !ok || v[0] != "" {
+		t.Errorf("X-Empty should be present and empty, got %v", v)
+	}
+	if got := req.Header.Values("X-Multi"); len(got) != 2 {
+		t.Errorf("X-Multi = %v; want two values", got)
+	}
+	if req.Host != "other.example" {
+		t.Errorf("Host = %q; want other.example", req.Host)
+	}
+}
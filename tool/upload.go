@@ -0,0 +1,162 @@
+package tool
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// This file contains the -T/--upload-file support of
+// curl-src/src/tool_operate.c: the local file name added to URLs that have
+// none, and the opening of the file, or stdin, to upload.
+
+// stdinUpload reports whether the -T argument name means stdin: "-" reads
+// it with blocking reads and "." without blocking, like the C function
+// `stdin_upload`.
+func stdinUpload(name string) bool {
+	return name == "-" || name == "."
+}
+
+// addFileNameToURL returns rawURL with the base name of the upload file
+// added when the URL has no file name part, that is when its path is empty
+// or ends with a slash. It is the C function `add_file_name_to_url`.
+func addFileNameToURL(rawURL, filename string) string {
+	end := len(rawURL)
+	if i := strings.IndexAny(rawURL, "?#"); i >= 0 {
+		end = i
+	}
+	base, rest := rawURL[:end], rawURL[end:]
+
+	path := base
+	if i := strings.Index(base, "://"); i >= 0 {
+		path = base[i+3:]
+	}
+	slash := strings.LastIndexByte(path, '/')
+	switch {
+	case slash < 0:
+		base += "/"
+	case slash != len(path)-1:
+		return rawURL
+	}
+	return base + urlEscape(Basename(filename)) + rest
+}
+
+// openUpload opens the file to upload and returns it with its size, -1
+// when the size is not known and the body has to be sent chunked. A read
+// of stdin for "-T ." ends with ctx.
+func openUpload(ctx context.Context, name string) (io.ReadCloser, int64, error) {
+	switch name {
+	case "-":
+		return io.NopCloser(os.Stdin), -1, nil
+	case ".":
+		return &stdinReader{ctx: ctx, feed: sharedStdinFeed(), closed: make(chan struct{})}, -1, nil
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return file, -1, nil
+	}
+	return file, info.Size(), nil
+}
+
+// stdinFeed is a goroutine reading stdin into chunks. Where curl makes
+// stdin non-blocking for "-T .", this keeps a read that waits for input
+// from holding up the transfer. A read of stdin cannot be interrupted, so
+// the goroutine runs until stdin ends; it is shared by all the "-T ."
+// transfers of the process, which take the chunks in turn, so that a glob
+// of uploads does not leave one goroutine behind per transfer.
+type stdinFeed struct {
+	chunks chan []byte // closed when stdin ends
+	err    error       // the error that ended stdin, set before chunks is closed
+
+	mu   sync.Mutex
+	rest []byte // the part of a chunk not read by the transfer that took it
+}
+
+var (
+	stdinFeedMu   sync.Mutex
+	stdinFeedFile *os.File
+	stdinFeedCur  *stdinFeed
+)
+
+// sharedStdinFeed returns the feed of os.Stdin, starting it on first use.
+func sharedStdinFeed() *stdinFeed {
+	stdinFeedMu.Lock()
+	defer stdinFeedMu.Unlock()
+	if stdinFeedCur == nil || stdinFeedFile != os.Stdin {
+		f := &stdinFeed{chunks: make(chan []byte)}
+		go f.run(os.Stdin)
+		stdinFeedFile, stdinFeedCur = os.Stdin, f
+	}
+	return stdinFeedCur
+}
+
+func (f *stdinFeed) run(file *os.File) {
+	for {
+		buf := make([]byte, 32*1024)
+		n, err := file.Read(buf)
+		if n > 0 {
+			f.chunks <- buf[:n]
+		}
+		if err != nil {
+			f.err = err
+			close(f.chunks)
+			return
+		}
+	}
+}
+
+// stdinReader reads the stdin feed for one transfer. A read waiting for
+// input returns when the reader is closed, as is done when the transfer
+// ends, or when its context is done.
+type stdinReader struct {
+	ctx       context.Context
+	feed      *stdinFeed
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (r *stdinReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	f := r.feed
+	f.mu.Lock()
+	if len(f.rest) > 0 {
+		n := copy(p, f.rest)
+		f.rest = f.rest[n:]
+		f.mu.Unlock()
+		return n, nil
+	}
+	f.mu.Unlock()
+
+	select {
+	case chunk, ok := <-f.chunks:
+		if !ok {
+			return 0, f.err
+		}
+		n := copy(p, chunk)
+		f.mu.Lock()
+		f.rest = chunk[n:]
+		f.mu.Unlock()
+		return n, nil
+	case <-r.closed:
+		return 0, io.ErrClosedPipe
+	case <-r.ctx.Done():
+		return 0, r.ctx.Err()
+	}
+}
+
+func (r *stdinReader) Close() error {
+	r.closeOnce.Do(func() { close(r.closed) })
+	return nil
+}
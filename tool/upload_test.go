@@ -0,0 +1,106 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAddFileNameToURL(t *testing.T) {
+	testCases := []struct {
+		url, file, want string
+	}{
+		{"http://example.com", "dir/file.txt", "http://example.com/file.txt"},
+		{"http://example.com/", "file.txt", "http://example.com/file.txt"},
+		{"http://example.com/up/", `C:\dir\my file.txt`, "http://example.com/up/my%20file.txt"},
+		{"http://example.com/up/?x=1#top", "f", "http://example.com/up/f?x=1#top"},
+		{"http://example.com/name", "file.txt", "http://example.com/name"},
+		{"http://example.com/name?dir/", "file.txt", "http://example.com/name?dir/"},
+		{"example.com", "file.txt", "example.com/file.txt"},
+	}
+	for _, tc := range testCases {
+		if got := addFileNameToURL(tc.url, tc.file); got != tc.want {
+			t.Errorf("addFileNameToURL(%q, %q) = %q; want %q", tc.url, tc.file, got, tc.want)
+		}
+	}
+}
+
+func TestOpenUpload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "upload")
+	if err := os.WriteFile(file, []byte("12345"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, size, err := openUpload(context.Background(), file)
+	if err != nil {
+		t.Fatalf("openUpload() failed: %v", err)
+	}
+	data, _ := io.ReadAll(r)
+	r.Close()
+	if size != 5 || string(data) != "12345" {
+		t.Errorf("openUpload() = %q, size %d; want %q, size 5", data, size, "12345")
+	}
+
+	if _, _, err := openUpload(context.Background(), filepath.Join(t.TempDir(), "nope")); err == nil {
+		t.Error("openUpload() should fail for a missing file")
+	}
+
+	for _, name := range []string{"-", "."} {
+		if !stdinUpload(name) {
+			t.Errorf("stdinUpload(%q) = false", name)
+		}
+		r, size, err := openUpload(context.Background(), name)
+		if err != nil || size != -1 {
+			t.Errorf("openUpload(%q) size = %d, err = %v; want -1, nil", name, size, err)
+		}
+		r.Close()
+	}
+}
+
+func TestOpenUpload_NonBlockingStdin(t *testing.T) {
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdin
+	os.Stdin = pr
+	defer func() {
+		os.Stdin = saved
+		pw.Close()
+		pr.Close()
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r1, _, _ := openUpload(ctx, ".")
+	r2, _, _ := openUpload(context.Background(), ".")
+	if r1.(*stdinReader).feed != r2.(*stdinReader).feed {
+		t.Error("openUpload(\".\") started a second stdin feed")
+	}
+
+	// Waiting reads end with the context of the transfer or when closed.
+	cancel()
+	if _, err := r1.Read(make([]byte, 8)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Read() after cancel = %v; want %v", err, context.Canceled)
+	}
+	r2.Close()
+	if _, err := r2.Read(make([]byte, 8)); err != io.ErrClosedPipe {
+		t.Errorf("Read() after Close() = %v; want %v", err, io.ErrClosedPipe)
+	}
+
+	// A later transfer goes on where the previous one stopped.
+	pw.Write([]byte("hello world"))
+	pw.Close()
+	r3, _, _ := openUpload(context.Background(), ".")
+	buf := make([]byte, 5)
+	if n, _ := io.ReadFull(r3, buf); string(buf[:n]) != "hello" {
+		t.Errorf("Read() = %q; want %q", buf[:n], "hello")
+	}
+	r3.Close()
+	r4, _, _ := openUpload(context.Background(), ".")
+	if data, err := io.ReadAll(r4); string(data) != " world" || err != nil {
+		t.Errorf("ReadAll() = %q, %v; want %q, nil", data, err, " world")
+	}
+}
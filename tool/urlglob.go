@@ -2,153 +2,454 @@ package tool
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 )
 
+// globKind tells what a component of a URL glob is.
+type globKind int
+
+const (
+	globLiteral   globKind = iota // text copied as it is
+	globSet                       // {a,b,c}
+	globCharRange                 // [a-z:step]
+	globNumRange                  // [0-99:step], with optional zero padding
+)
+
 // globPattern represents a single component of a URL glob, which can be a
-// literal string, a set of options, or a character/numeric range.
+// literal string, a set of options, or a character/numeric range. It is
+// the C `struct URLPattern`.
 type globPattern struct {
-	options []string
+	kind    globKind
+	options []string // the text of a literal, or the elements of a set
+
+	// The first and last values of a range and the step between values.
+	// Character ranges hold the characters as numbers.
+	min, max, step int64
+	// padLength is the width numbers are padded to with zeros, when the
+	// first number of the range was written with a leading zero.
+	padLength int
 }
 
-// ExpandURLGlob takes a URL with globbing patterns and returns a slice of all
-// expanded URLs. This is the primary entry point for the globbing functionality.
-// It is a translation of the C function `glob_url` and its helpers from
-// curl-src/src/tool_urlglob.c.
-func ExpandURLGlob(pattern string) ([]string, error) {
+// size returns the number of values the component takes, or -1 when the
+// number does not fit in an int64, as for [0-9223372036854775807]. The
+// bounds of a range are never negative, so only the +1 can overflow.
+func (p *globPattern) size() int64 {
+	switch p.kind {
+	case globCharRange, globNumRange:
+		n := (p.max - p.min) / p.step
+		if n == math.MaxInt64 {
+			return -1
+		}
+		return n + 1
+	default:
+		return int64(len(p.options))
+	}
+}
+
+// value returns the ith value of the component.
+func (p *globPattern) value(i int64) string {
+	switch p.kind {
+	case globCharRange:
+		return string(rune(p.min + i*p.step))
+	case globNumRange:
+		return fmt.Sprintf("%0*d", p.padLength, p.min+i*p.step)
+	default:
+		return p.options[i]
+	}
+}
+
+// GlobURL is a URL expanded from a glob, with the values the glob's sets
+// and ranges took for it. Captures[0] is the value "#1" refers to in an
+// output file name.
+type GlobURL struct {
+	URL      string
+	Captures []string
+}
+
+// URLGlob iterates over the URLs a glob expands to, one at a time, so that
+// a glob matching millions of URLs does not take memory up front. It is
+// the C `struct URLGlob`, with Next playing the role of `glob_next_url`.
+type URLGlob struct {
+	patterns []globPattern
+	indexes  []int64 // the index of the current value of each pattern
+	total    int64
+	started  bool
+	done     bool
+}
+
+// NewURLGlob parses a URL with globbing patterns. It is a translation of
+// the C function `glob_url` and its helpers from
+// curl-src/src/tool_urlglob.c. The number of URLs is worked out up front,
+// and a glob matching more than fit in an int64 is an error.
+func NewURLGlob(pattern string) (*URLGlob, error) {
 	patterns, err := parse(pattern)
 	if err != nil {
 		return nil, err
 	}
+	return newURLGlob(patterns)
+}
+
+// literalGlob returns a glob that yields s as it is, for URLs that are
+// not globbed.
+func literalGlob(s string) *URLGlob {
+	g, _ := newURLGlob([]globPattern{{options: []string{s}}})
+	return g
+}
+
+func newURLGlob(patterns []globPattern) (*URLGlob, error) {
+	total := int64(1)
+	for i := range patterns {
+		size := patterns[i].size()
+		if size < 0 || size > 0 && total > math.MaxInt64/size {
+			return nil, fmt.Errorf("too many URLs")
+		}
+		total *= size
+	}
+	return &URLGlob{patterns: patterns, indexes: make([]int64, len(patterns)), total: total}, nil
+}
+
+// Total returns the number of URLs the glob expands to.
+func (g *URLGlob) Total() int64 {
+	return g.total
+}
+
+// Next returns the next URL of the glob, with the values of its sets and
+// ranges. It returns false once every URL has been returned. The last
+// pattern varies fastest, so "{a,b}[1-2]" gives a1, a2, b1 and b2.
+func (g *URLGlob) Next() (GlobURL, bool) {
+	if g.done {
+		return GlobURL{}, false
+	}
+	if !g.started {
+		g.started = true
+		if g.total == 0 {
+			g.done = true
+			return GlobURL{}, false
+		}
+	} else {
+		i := len(g.indexes) - 1
+		for ; i >= 0; i-- {
+			g.indexes[i]++
+			if g.indexes[i] < g.patterns[i].size() {
+				break
+			}
+			g.indexes[i] = 0
+		}
+		if i < 0 {
+			// Every pattern wrapped around: all the URLs have been seen.
+			g.done = true
+			return GlobURL{}, false
+		}
+	}
 
-	if len(patterns) == 0 {
-		return []string{""}, nil
+	var url strings.Builder
+	var captures []string
+	for i := range g.patterns {
+		p := &g.patterns[i]
+		value := p.value(g.indexes[i])
+		url.WriteString(value)
+		if p.kind != globLiteral {
+			captures = append(captures, value)
+		}
 	}
+	return GlobURL{URL: url.String(), Captures: captures}, true
+}
 
-	var results []string
-	generate(&results, patterns, "", 0)
+// Reset makes the glob start over from its first URL.
+func (g *URLGlob) Reset() {
+	clear(g.indexes)
+	g.started, g.done = false, false
+}
+
+// ExpandURLGlob takes a URL with globbing patterns and returns a slice of all
+// expanded URLs. Large globs are better walked with a URLGlob.
+func ExpandURLGlob(pattern string) ([]GlobURL, error) {
+	g, err := NewURLGlob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var results []GlobURL
+	for u, ok := g.Next(); ok; u, ok = g.Next() {
+		results = append(results, u)
+	}
 	return results, nil
 }
 
+// GlobError is a syntax error in a URL glob. Column is the position in
+// the URL, counted from 1, of the character the error is about.
+type GlobError struct {
+	Message string
+	Column  int
+}
+
+func (e *GlobError) Error() string {
+	return fmt.Sprintf("%s in column %d", e.Message, e.Column)
+}
+
+// globErrorAt returns a GlobError about the character at offset pos.
+func globErrorAt(message string, pos int) *GlobError {
+	return &GlobError{Message: message, Column: pos + 1}
+}
+
 // parse iterates through the URL pattern and breaks it down into a series of
-// globPattern structs.
+// globPattern structs. It is the C function `glob_parse`. Outside of sets, a
+// backslash only escapes the glob characters {, }, [ and ], and other
+// backslashes are kept. A closing brace or bracket with no opening one is
+// an error, unless it closes an escaped one, so that escaping the opening
+// one is enough.
 func parse(pattern string) ([]globPattern, error) {
 	var patterns []globPattern
 	var currentLiteral strings.Builder
+	escaped := map[byte]int{} // the escaped opening braces and brackets not closed yet
+	flush := func() {
+		if currentLiteral.Len() > 0 {
+			patterns = append(patterns, globPattern{options: []string{currentLiteral.String()}})
+			currentLiteral.Reset()
+		}
+	}
 
-	for i := 0; i < len(pattern); i++ {
-		char := pattern[i]
+	for pos := 0; pos < len(pattern); {
+		char := pattern[pos]
 
-		switch char {
-		case '\\':
-			// Handle escaped characters
-			i++
-			if i < len(pattern) {
-				currentLiteral.WriteByte(pattern[i])
+		switch {
+		case char == '\\' && pos+1 < len(pattern) && strings.IndexByte("{}[]", pattern[pos+1]) >= 0:
+			if c := pattern[pos+1]; c == '{' || c == '[' {
+				escaped[c]++
 			}
-		case '{':
-			// Flush the current literal part
-			if currentLiteral.Len() > 0 {
-				patterns = append(patterns, globPattern{options: []string{currentLiteral.String()}})
-				currentLiteral.Reset()
+			currentLiteral.WriteByte(pattern[pos+1])
+			pos += 2
+		case char == '}' && escaped['{'] > 0, char == ']' && escaped['['] > 0:
+			if char == '}' {
+				escaped['{']--
+			} else {
+				escaped['[']--
 			}
-			// Parse the set
-			set, remainder, err := parseSet(pattern[i+1:])
+			currentLiteral.WriteByte(char)
+			pos++
+		case char == '{':
+			flush()
+			set, next, err := parseSet(pattern, pos)
 			if err != nil {
 				return nil, err
 			}
 			patterns = append(patterns, set)
-			pattern = remainder
-			i = -1 // Restart loop at the beginning of the new remainder
-		case '[':
-			// Flush the current literal part
-			if currentLiteral.Len() > 0 {
-				patterns = append(patterns, globPattern{options: []string{currentLiteral.String()}})
-				currentLiteral.Reset()
+			pos = next
+		case char == '[':
+			// An IPv6 address is not a range, and neither is "[]".
+			n := ipv6Literal(pattern[pos:])
+			if n == 0 && strings.HasPrefix(pattern[pos:], "[]") {
+				n = 2
+			}
+			if n > 0 {
+				currentLiteral.WriteString(pattern[pos : pos+n])
+				pos += n
+				continue
 			}
-			// Parse the range
-			rangePattern, remainder, err := parseRange(pattern[i+1:])
+			flush()
+			rangePattern, next, err := parseRange(pattern, pos)
 			if err != nil {
 				return nil, err
 			}
 			patterns = append(patterns, rangePattern)
-			pattern = remainder
-			i = -1 // Restart loop
+			pos = next
+		case char == '}' || char == ']':
+			return nil, globErrorAt("unmatched close brace/bracket", pos)
 		default:
 			currentLiteral.WriteByte(char)
+			pos++
 		}
 	}
-
-	// Add any remaining literal part
-	if currentLiteral.Len() > 0 {
-		patterns = append(patterns, globPattern{options: []string{currentLiteral.String()}})
-	}
+	flush()
 
 	return patterns, nil
 }
 
-// parseSet handles patterns like {a,b,c}.
-func parseSet(pattern string) (globPattern, string, error) {
-	end := strings.IndexByte(pattern, '}')
-	if end == -1 {
-		return globPattern{}, "", fmt.Errorf("unmatched brace in glob")
+// ipv6Literal returns the length of the bracketed IPv6 address, such as
+// "[::1]" or "[fe80::1%25eth0]", that s starts with, or 0 when it does not
+// start with one. Like curl, it tells addresses from ranges by their
+// colons: an address has at least two, a range at most one.
+func ipv6Literal(s string) int {
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return 0
 	}
-	content := pattern[:end]
-	options := strings.Split(content, ",")
-	return globPattern{options: options}, pattern[end+1:], nil
-}
-
-// parseRange handles patterns like [a-z] or [0-9].
-func parseRange(pattern string) (globPattern, string, error) {
-	end := strings.IndexByte(pattern, ']')
-	if end == -1 {
-		return globPattern{}, "", fmt.Errorf("unmatched bracket in glob")
+	address, zone, _ := strings.Cut(s[1:end], "%")
+	for i := 0; i < len(address); i++ {
+		c := address[i]
+		if !isHexDigit(c) && c != ':' && c != '.' {
+			return 0
+		}
 	}
-	content := pattern[:end]
-	parts := strings.Split(content, "-")
-	if len(parts) != 2 {
-		return globPattern{}, "", fmt.Errorf("invalid range format: %s", content)
+	for i := 0; i < len(zone); i++ {
+		c := zone[i]
+		if !isLetter(c) && !isDigit(c) && !strings.ContainsRune("-._~%", rune(c)) {
+			return 0
+		}
 	}
+	if strings.Count(address, ":") < 2 {
+		return 0
+	}
+	return end + 1
+}
 
-	start, endRange := parts[0], parts[1]
+// parseSet handles patterns like {a,b,c}, the set opening at offset open
+// of pattern. It returns the offset following the set. Within a set, a
+// backslash escapes any character, so "{a\\,b,c}" has the elements "a,b"
+// and "c". It is the C function `glob_set`.
+func parseSet(pattern string, open int) (globPattern, int, error) {
 	var options []string
-
-	// Check if it's a numeric range
-	if startNum, err1 := strconv.Atoi(start); err1 == nil {
-		if endNum, err2 := strconv.Atoi(endRange); err2 == nil {
-			if startNum > endNum {
-				return globPattern{}, "", fmt.Errorf("invalid numeric range: start > end")
+	var elem strings.Builder
+	for pos := open + 1; pos < len(pattern); pos++ {
+		switch c := pattern[pos]; c {
+		case '{', '[':
+			return globPattern{}, 0, globErrorAt("nested brace", pos)
+		case ']':
+			return globPattern{}, 0, globErrorAt("unexpected close bracket", pos)
+		case ',', '}':
+			options = append(options, elem.String())
+			elem.Reset()
+			if c == '}' {
+				if pos == open+1 {
+					return globPattern{}, 0, globErrorAt("empty string within braces", open)
+				}
+				return globPattern{kind: globSet, options: options}, pos + 1, nil
 			}
-			for i := startNum; i <= endNum; i++ {
-				options = append(options, strconv.Itoa(i))
+		case '\\':
+			if pos+1 < len(pattern) {
+				pos++
 			}
-			return globPattern{options: options}, pattern[end+1:], nil
+			elem.WriteByte(pattern[pos])
+		default:
+			elem.WriteByte(c)
 		}
 	}
+	return globPattern{}, 0, globErrorAt("unmatched brace", open)
+}
 
-	// Assume character range
-	if len(start) != 1 || len(endRange) != 1 {
-		return globPattern{}, "", fmt.Errorf("invalid character range format")
+// parseRange handles patterns like [a-z], [0-9] and [001-100:10]: a range
+// of letters or numbers with an optional step, opening at offset open of
+// pattern. It returns the offset following the range. A first number
+// written with leading zeros sets the width all the numbers are padded to.
+// It is the C function `glob_range`, and reports errors the same way: "bad
+// range specification" for letters and "bad range" for numbers.
+func parseRange(pattern string, open int) (globPattern, int, error) {
+	badSpec := globErrorAt("bad range specification", open)
+	content, _, found := strings.Cut(pattern[open+1:], "]")
+	if content == "" {
+		return globPattern{}, 0, badSpec
 	}
-	startChar, endChar := start[0], endRange[0]
-	if startChar > endChar {
-		return globPattern{}, "", fmt.Errorf("invalid character range: start > end")
+	next := open + 1 + len(content) + 1
+
+	if isLetter(content[0]) {
+		// [a-z] or [a-z:step]
+		if !found || len(content) < 3 || content[1] != '-' || !isLetter(content[2]) {
+			return globPattern{}, 0, badSpec
+		}
+		step := int64(1)
+		if rest := content[3:]; rest != "" {
+			var ok bool
+			if step, ok = parseRangeStep(rest); !ok {
+				return globPattern{}, 0, badSpec
+			}
+		}
+		startChar, endChar := content[0], content[2]
+		if startChar > endChar {
+			return globPattern{}, 0, badSpec
+		}
+		p := globPattern{kind: globCharRange, min: int64(startChar), max: int64(endChar), step: step}
+		return p, next, nil
 	}
-	for c := startChar; c <= endChar; c++ {
-		options = append(options, string(c))
+
+	if !isDigit(content[0]) {
+		return globPattern{}, 0, badSpec
+	}
+	// [0-9], [001-100] or [0-100:step]
+	bad := globErrorAt("bad range", open)
+	bounds, stepText, hasStep := strings.Cut(content, ":")
+	start, endRange, hasEnd := strings.Cut(bounds, "-")
+	if !found || !hasEnd || !isDigits(start) || !isDigits(endRange) {
+		return globPattern{}, 0, bad
+	}
+	step := int64(1)
+	if hasStep {
+		var ok bool
+		if step, ok = parseRangeStep(":" + stepText); !ok {
+			return globPattern{}, 0, bad
+		}
+	}
+	startNum, err1 := strconv.ParseInt(start, 10, 64)
+	endNum, err2 := strconv.ParseInt(endRange, 10, 64)
+	if err1 != nil || err2 != nil {
+		return globPattern{}, 0, globErrorAt("range overflow", open)
+	}
+	if startNum > endNum {
+		return globPattern{}, 0, bad
 	}
-	return globPattern{options: options}, pattern[end+1:], nil
+	p := globPattern{kind: globNumRange, min: startNum, max: endNum, step: step}
+	if len(start) > 1 && start[0] == '0' {
+		p.padLength = len(start)
+	}
+	return p, next, nil
 }
 
-// generate recursively builds the final URL strings from the parsed patterns.
-func generate(results *[]string, patterns []globPattern, current string, index int) {
-	if index == len(patterns) {
-		*results = append(*results, current)
-		return
+// parseRangeStep parses the ":step" ending a range. The step must be at
+// least 1.
+func parseRangeStep(s string) (int64, bool) {
+	text, ok := strings.CutPrefix(s, ":")
+	if !ok || !isDigits(text) {
+		return 0, false
+	}
+	step, err := strconv.ParseInt(text, 10, 64)
+	return step, err == nil && step >= 1
+}
+
+// isDigits reports whether s is made of decimal digits only.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
 	}
+	return s != ""
+}
+
+// isDigit reports whether c is a decimal digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
 
-	for _, option := range patterns[index].options {
-		generate(results, patterns, current+option, index+1)
+// isHexDigit reports whether c is a hexadecimal digit.
+func isHexDigit(c byte) bool {
+	return isDigit(c) || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// globMatchURL fills in an output file name for an expanded URL: each
+// "#N" is replaced with the value the Nth set or range of the glob took.
+// A "#" not followed by the number of a glob is kept as it is. It is the C
+// function `glob_match_url`.
+func globMatchURL(filename string, captures []string) string {
+	var b strings.Builder
+	for i := 0; i < len(filename); i++ {
+		c := filename[i]
+		if c == '#' {
+			j := i + 1
+			for j < len(filename) && filename[j] >= '0' && filename[j] <= '9' {
+				j++
+			}
+			if n, err := strconv.Atoi(filename[i+1 : j]); err == nil && n >= 1 && n <= len(captures) {
+				b.WriteString(captures[n-1])
+				i = j - 1
+				continue
+			}
+		}
+		b.WriteByte(c)
 	}
-}
\ No newline at end of file
+	return b.String()
+}
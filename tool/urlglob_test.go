@@ -1,8 +1,11 @@
 package tool
 
 import (
+	"errors"
+	"math"
 	"reflect"
 	"sort"
+	"strings"
 	"testing"
 )
 
@@ -48,6 +51,61 @@ func TestExpandURLGlob(t *testing.T) {
 			pattern:  `http://example.com/\[1-3]`,
 			expected: []string{`http://example.com/[1-3]`},
 		},
+		{
+			name:     "empty brackets",
+			pattern:  "http://example.com/a[]{1,2}",
+			expected: []string{"http://example.com/a[]1", "http://example.com/a[]2"},
+		},
+		{
+			name:     "numeric range with step",
+			pattern:  "http://example.com/[0-100:25]",
+			expected: []string{"http://example.com/0", "http://example.com/25", "http://example.com/50", "http://example.com/75", "http://example.com/100"},
+		},
+		{
+			name:     "zero padded range",
+			pattern:  "http://example.com/[008-011:1]",
+			expected: []string{"http://example.com/008", "http://example.com/009", "http://example.com/010", "http://example.com/011"},
+		},
+		{
+			name:     "character range with step",
+			pattern:  "http://example.com/[a-g:3]",
+			expected: []string{"http://example.com/a", "http://example.com/d", "http://example.com/g"},
+		},
+		{
+			name:     "IPv6 address",
+			pattern:  "http://[::1]:8080/[1-2]",
+			expected: []string{"http://[::1]:8080/1", "http://[::1]:8080/2"},
+		},
+		{
+			name:     "IPv6 address with zone",
+			pattern:  "http://[fe80::1%25eth0]/",
+			expected: []string{"http://[fe80::1%25eth0]/"},
+		},
+		{
+			name:     "escaped comma and brace in set",
+			pattern:  `http://example.com/{a\,b,c\}}`,
+			expected: []string{"http://example.com/a,b", "http://example.com/c}"},
+		},
+		{
+			name:     "other backslashes kept",
+			pattern:  `http://example.com/a\b`,
+			expected: []string{`http://example.com/a\b`},
+		},
+		{
+			name:    "zero step",
+			pattern: "http://example.com/[1-9:0]",
+			wantErr: true,
+		},
+		{
+			name:    "bad step",
+			pattern: "http://example.com/[1-9:x]",
+			wantErr: true,
+		},
+		{
+			name:    "mixed range",
+			pattern: "http://example.com/[1-z]",
+			wantErr: true,
+		},
 		{
 			name:    "unmatched brace",
 			pattern: "http://example.com/{a,b",
@@ -67,11 +125,15 @@ func TestExpandURLGlob(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			result, err := ExpandURLGlob(tc.pattern)
+			expanded, err := ExpandURLGlob(tc.pattern)
 
 			if (err != nil) != tc.wantErr {
 				t.Fatalf("ExpandURLGlob() error = %v, wantErr %v", err, tc.wantErr)
 			}
+			var result []string
+			for _, g := range expanded {
+				result = append(result, g.URL)
+			}
 
 			// Sort both slices for stable comparison
 			sort.Strings(result)
@@ -82,4 +144,123 @@ func TestExpandURLGlob(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestExpandURLGlob_Captures(t *testing.T) {
+	expanded, err := ExpandURLGlob("http://{one,two}.example.com/shard[01-02].bin")
+	if err != nil {
+		t.Fatalf("ExpandURLGlob() failed: %v", err)
+	}
+	want := []GlobURL{
+		{"http://one.example.com/shard01.bin", []string{"one", "01"}},
+		{"http://one.example.com/shard02.bin", []string{"one", "02"}},
+		{"http://two.example.com/shard01.bin", []string{"two", "01"}},
+		{"http://two.example.com/shard02.bin", []string{"two", "02"}},
+	}
+	if !reflect.DeepEqual(expanded, want) {
+		t.Errorf("ExpandURLGlob() = %v; want %v", expanded, want)
+	}
+}
+
+func TestGlobMatchURL(t *testing.T) {
+	captures := []string{"one", "01"}
+	testCases := []struct {
+		filename string
+		want     string
+	}{
+		{"file_#1_#2.txt", "file_one_01.txt"},
+		{"#2#1", "01one"},
+		{"#3.txt", "#3.txt"},
+		{"#0 and #", "#0 and #"},
+		{"no captures", "no captures"},
+	}
+	for _, tc := range testCases {
+		if got := globMatchURL(tc.filename, captures); got != tc.want {
+			t.Errorf("globMatchURL(%q) = %q; want %q", tc.filename, got, tc.want)
+		}
+	}
+}
+
+func TestURLGlob(t *testing.T) {
+	g, err := NewURLGlob("http://example.com/{a,b}/[1-1000000000]")
+	if err != nil {
+		t.Fatalf("NewURLGlob() failed: %v", err)
+	}
+	if g.Total() != 2000000000 {
+		t.Errorf("Total() = %d; want 2000000000", g.Total())
+	}
+	// The URLs are made one at a time.
+	for _, want := range []string{"http://example.com/a/1", "http://example.com/a/2", "http://example.com/a/3"} {
+		if u, ok := g.Next(); !ok || u.URL != want {
+			t.Errorf("Next() = %q, %v; want %q", u.URL, ok, want)
+		}
+	}
+
+	g, err = NewURLGlob("{x,y}[1-2]")
+	if err != nil {
+		t.Fatalf("NewURLGlob() failed: %v", err)
+	}
+	for round := 0; round < 2; round++ {
+		var got []string
+		for u, ok := g.Next(); ok; u, ok = g.Next() {
+			got = append(got, u.URL)
+		}
+		if want := []string{"x1", "x2", "y1", "y2"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("round %d: URLs = %q; want %q", round, got, want)
+		}
+		if _, ok := g.Next(); ok {
+			t.Error("Next() after the last URL should return false")
+		}
+		g.Reset()
+	}
+
+	// 10^19 URLs do not fit in an int64.
+	if _, err := NewURLGlob(strings.Repeat("[0-9]", 19)); err == nil || err.Error() != "too many URLs" {
+		t.Errorf("NewURLGlob() error = %v; want too many URLs", err)
+	}
+	if _, err := NewURLGlob(strings.Repeat("[0-9]", 18)); err != nil {
+		t.Errorf("NewURLGlob() of 10^18 URLs failed: %v", err)
+	}
+	for _, pattern := range []string{
+		"http://x/[0-9223372036854775807]",
+		"http://x/[1-4294967296][1-4294967296]",
+	} {
+		if _, err := NewURLGlob(pattern); err == nil || err.Error() != "too many URLs" {
+			t.Errorf("NewURLGlob(%q) error = %v; want too many URLs", pattern, err)
+		}
+	}
+	if g, err := NewURLGlob("http://x/[1-9223372036854775807]"); err != nil {
+		t.Errorf("NewURLGlob() of MaxInt64 URLs failed: %v", err)
+	} else if g.Total() != math.MaxInt64 {
+		t.Errorf("Total() = %d; want %d", g.Total(), int64(math.MaxInt64))
+	}
+}
+
+func TestExpandURLGlob_Errors(t *testing.T) {
+	testCases := []struct {
+		pattern string
+		want    string
+	}{
+		{"http://example.com/{a,b", "unmatched brace in column 20"},
+		{"http://example.com/{a,{b}}", "nested brace in column 23"},
+		{"http://example.com/{}", "empty string within braces in column 20"},
+		{"http://example.com/{a]}", "unexpected close bracket in column 22"},
+		{"http://example.com/{a,[1-2]}", "nested brace in column 23"},
+		{"http://example.com/a]", "unmatched close brace/bracket in column 21"},
+		{"http://example.com/a}b", "unmatched close brace/bracket in column 21"},
+		{"http://example.com/[1-5", "bad range in column 20"},
+		{"http://example.com/[5-1]", "bad range in column 20"},
+		{"http://example.com/[1-5:0]", "bad range in column 20"},
+		{"http://example.com/x[a-5]", "bad range specification in column 21"},
+		{"http://example.com/[z-a]", "bad range specification in column 20"},
+		{"http://example.com/[%]", "bad range specification in column 20"},
+		{"http://example.com/[1-99999999999999999999]", "range overflow in column 20"},
+	}
+	for _, tc := range testCases {
+		_, err := ExpandURLGlob(tc.pattern)
+		var globErr *GlobError
+		if !errors.As(err, &globErr) || err.Error() != tc.want {
+			t.Errorf("ExpandURLGlob(%q) error = %v; want %q", tc.pattern, err, tc.want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tool
+
+import (
+	"fmt"
+	"strings"
+)
+
+// This file holds the URL changes curl makes after globbing and before a
+// transfer starts: the -d data, with -G/--get, and the --url-query parts
+// are added to the query of each URL. In curl-src/src/tool_operate.c this
+// is done with `curl_url_set(..., CURLU_APPENDQUERY)`.
+
+// transferURL returns the URL to fetch for rawURL under config. As in
+// curl, the -G data comes before the --url-query parts.
+func transferURL(config *OperationConfig, rawURL string) string {
+	var query string
+	if config.UseHTTPGet {
+		query = config.PostFields
+	}
+	if config.Query != "" {
+		if query != "" {
+			query += "&"
+		}
+		query += config.Query
+	}
+	return appendQuery(rawURL, escapeQuery(query))
+}
+
+// escapeQuery makes the query parts added to a URL fit to be sent. The
+// --url-query parts starting with '+' and the -G data are added as they
+// are, and like libcurl's URL parser, a space in them is turned into a '+'
+// and the other bytes not allowed in a URL are percent-encoded. It is the
+// query half of the C function `urlencode_str` from lib/urlapi.c.
+func escapeQuery(query string) string {
+	var b strings.Builder
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == ' ':
+			b.WriteByte('+')
+		case c < ' ' || c >= 0x7f:
+			fmt.Fprintf(&b, "%%%02X", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// appendQuery adds query to the query string of rawURL, inserting a '?' or
+// '&' as needed. A fragment stays at the end of the URL.
+func appendQuery(rawURL, query string) string {
+	if query == "" {
+		return rawURL
+	}
+	base, fragment, hasFragment := strings.Cut(rawURL, "#")
+	switch {
+	case !strings.Contains(base, "?"):
+		base += "?"
+	case !strings.HasSuffix(base, "?") && !strings.HasSuffix(base, "&"):
+		base += "&"
+	}
+	base += query
+	if hasFragment {
+		base += "#" + fragment
+	}
+	return base
+}
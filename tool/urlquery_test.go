@@ -0,0 +1,49 @@
+package tool
+
+import "testing"
+
+func TestAppendQuery(t *testing.T) {
+	testCases := []struct {
+		url   string
+		query string
+		want  string
+	}{
+		{"http://a/path", "x=1", "http://a/path?x=1"},
+		{"http://a/path?y=2", "x=1", "http://a/path?y=2&x=1"},
+		{"http://a/path?", "x=1", "http://a/path?x=1"},
+		{"http://a/path?y=2&", "x=1", "http://a/path?y=2&x=1"},
+		{"http://a/path#frag", "x=1", "http://a/path?x=1#frag"},
+		{"http://a/path?y=2#frag?z", "x=1", "http://a/path?y=2&x=1#frag?z"},
+		{"http://a/path", "", "http://a/path"},
+	}
+	for _, tc := range testCases {
+		if got := appendQuery(tc.url, tc.query); got != tc.want {
+			t.Errorf("appendQuery(%q, %q) = %q; want %q", tc.url, tc.query, got, tc.want)
+		}
+	}
+}
+
+func TestTransferURL(t *testing.T) {
+	testCases := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"no query", []string{"-d", "a=1"}, "http://h/p"},
+		{"get moves data", []string{"-G", "-d", "a=1", "-d", "b=2"}, "http://h/p?a=1&b=2"},
+		{"url-query", []string{"--url-query", "q=a b", "--url-query", "+raw=a b"}, "http://h/p?q=a%20b&raw=a+b"},
+		{"raw bytes escaped", []string{"--url-query", "+r=\t\xe9", "-G", "-d", "d=x y"}, "http://h/p?d=x+y&r=%09%E9"},
+		{"data before url-query", []string{"-G", "-d", "a=1", "--url-query", "=x"}, "http://h/p?a=1&x"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			global := NewGlobalConfig()
+			if err := NewParameterParser(global).Parse(tc.args); err != nil {
+				t.Fatalf("Parse() failed: %v", err)
+			}
+			if got := transferURL(global.Last, "http://h/p"); got != tc.want {
+				t.Errorf("transferURL() = %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
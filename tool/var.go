@@ -0,0 +1,218 @@
+package tool
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// This file contains the Go translation of curl-src/src/var.c: the
+// variables set with --variable and their expansion in the arguments of
+// --expand-<option> options.
+
+// maxVarNameLen is the C `MAX_VAR_LEN`, the longest variable name accepted.
+const maxVarNameLen = 128
+
+// validVarName reports whether name is a valid variable name: one or more
+// letters, digits and underscores.
+func validVarName(name string) bool {
+	if name == "" || len(name) > maxVarNameLen {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// varNameLen returns the length of the variable name at the start of s.
+func varNameLen(s string) int {
+	n := 0
+	for n < len(s) && n < maxVarNameLen && validVarName(s[n:n+1]) {
+		n++
+	}
+	return n
+}
+
+// readVarFile returns the content of the file a variable is set from, "-"
+// meaning stdin.
+func readVarFile(name string) (string, error) {
+	var content []byte
+	var err error
+	if name == "-" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return "", paramErrorf(ParamReadError, "%v", err)
+	}
+	return string(content), nil
+}
+
+// setVariable handles the argument of --variable. It is the Go equivalent
+// of the C function `setvariable` and accepts these forms:
+//
+//	name=content    set name to content
+//	name@file       set name to the content of file, "-" being stdin
+//	%NAME           import the environment variable NAME
+//	%NAME=default   import NAME, using default when it is not set
+//	%NAME@file      import NAME, using the content of file when it is not set
+func setVariable(global *GlobalConfig, input string) error {
+	importEnv := strings.HasPrefix(input, "%")
+	if importEnv {
+		input = input[1:]
+	}
+
+	n := varNameLen(input)
+	name, rest := input[:n], input[n:]
+	if name == "" || (rest != "" && rest[0] != '=' && rest[0] != '@') || (!importEnv && rest == "") {
+		return paramErrorf(ParamVarSyntax, "Bad variable name: %s", input)
+	}
+
+	var value string
+	found := false
+	if importEnv {
+		value, found = os.LookupEnv(name)
+	}
+	if !found {
+		switch {
+		case rest == "":
+			return paramErrorf(ParamExpandError, "Variable '%s' import fail, not set", name)
+		case rest[0] == '@':
+			content, err := readVarFile(rest[1:])
+			if err != nil {
+				return err
+			}
+			value = content
+		default:
+			value = rest[1:]
+		}
+	}
+
+	if global.Variables == nil {
+		global.Variables = make(map[string]string)
+	}
+	if _, exists := global.Variables[name]; exists {
+		global.messager().Warnf("Overwriting variable '%s'", name)
+	}
+	global.Variables[name] = value
+	return nil
+}
+
+// varFunctions are the functions that can be applied to a variable in an
+// expansion, as in "{{name:trim:url}}".
+var varFunctions = map[string]func(string) string{
+	"trim": func(s string) string { return strings.TrimSpace(s) },
+	"json": jsonEscape,
+	"url":  urlEscape,
+	"b64":  func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+}
+
+// jsonEscape escapes s for use inside a JSON string, without the quotes.
+func jsonEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(&sb, `\u%04x`, r)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// urlEscape percent-encodes every byte of s except the unreserved
+// characters, like `curl_easy_escape`.
+func urlEscape(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '.' || c == '_' || c == '~' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	return sb.String()
+}
+
+// expandVariables replaces every "{{name}}" in input with the value of the
+// variable, after applying the functions listed after the name, and
+// returns the result. It is the Go equivalent of the C function
+// `varexpand`. A variable that is not set expands to nothing, "\{{" gives a
+// literal "{{", and text in braces that is not a variable name is kept as
+// it is. An unknown function or a value with a zero byte that is not
+// encoded by the last function is an error.
+func expandVariables(global *GlobalConfig, input string) (string, error) {
+	var sb strings.Builder
+	for {
+		open := strings.Index(input, "{{")
+		if open < 0 {
+			sb.WriteString(input)
+			return sb.String(), nil
+		}
+		if open > 0 && input[open-1] == '\\' {
+			sb.WriteString(input[:open-1])
+			sb.WriteString("{{")
+			input = input[open+2:]
+			continue
+		}
+		sb.WriteString(input[:open])
+		input = input[open+2:]
+
+		end := strings.Index(input, "}}")
+		n := varNameLen(input)
+		if end < 0 || n == 0 || (n != end && input[n] != ':') {
+			// Not a variable reference.
+			sb.WriteString("{{")
+			continue
+		}
+		name, funcs := input[:n], input[n:end]
+		input = input[end+2:]
+
+		value, ok := global.Variables[name]
+		if !ok {
+			global.messager().Notef("variable '%s' is not set", name)
+		}
+		encoded := false
+		for _, fn := range strings.Split(strings.TrimPrefix(funcs, ":"), ":") {
+			if fn == "" {
+				continue
+			}
+			f, ok := varFunctions[fn]
+			if !ok {
+				return "", paramErrorf(ParamExpandError, "unknown variable function in '%s'", name+funcs)
+			}
+			value = f(value)
+			encoded = fn != "trim"
+		}
+		if !encoded && strings.IndexByte(value, 0) >= 0 {
+			return "", paramErrorf(ParamExpandError, "variable contains null byte")
+		}
+		sb.WriteString(value)
+	}
+}
@@ -0,0 +1,157 @@
+package tool
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetVariable(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "content.txt")
+	if err := os.WriteFile(file, []byte("from file\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CURL_TEST_HOST", "example.com")
+	os.Unsetenv("CURL_TEST_UNSET")
+
+	testCases := []struct {
+		input string
+		name  string
+		want  string
+	}{
+		{"plain=value", "plain", "value"},
+		{"empty=", "empty", ""},
+		{"with_eq=a=b", "with_eq", "a=b"},
+		{"fromfile@" + file, "fromfile", "from file\n"},
+		{"%CURL_TEST_HOST", "CURL_TEST_HOST", "example.com"},
+		{"%CURL_TEST_HOST=default", "CURL_TEST_HOST", "example.com"},
+		{"%CURL_TEST_UNSET=fallback", "CURL_TEST_UNSET", "fallback"},
+		{"%CURL_TEST_UNSET@" + file, "CURL_TEST_UNSET", "from file\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			global, _, _ := newTestGlobal()
+			if err := setVariable(global, tc.input); err != nil {
+				t.Fatalf("setVariable() failed: %v", err)
+			}
+			if got, ok := global.Variables[tc.name]; !ok || got != tc.want {
+				t.Errorf("Variables[%q] = %q, %v; want %q", tc.name, got, ok, tc.want)
+			}
+		})
+	}
+
+	errorCases := []struct {
+		input string
+		want  ParameterError
+	}{
+		{"noequals", ParamVarSyntax},
+		{"bad-name=x", ParamVarSyntax},
+		{"=x", ParamVarSyntax},
+		{"%", ParamVarSyntax},
+		{"%CURL_TEST_UNSET", ParamExpandError},
+		{"missing@" + filepath.Join(dir, "nope"), ParamReadError},
+	}
+	for _, tc := range errorCases {
+		t.Run(tc.input, func(t *testing.T) {
+			global, _, _ := newTestGlobal()
+			if err := setVariable(global, tc.input); !errors.Is(err, tc.want) {
+				t.Errorf("setVariable() error = %v; want %v", err, tc.want)
+			}
+		})
+	}
+
+	t.Run("overwrite warns", func(t *testing.T) {
+		global, _, stderr := newTestGlobal()
+		setVariable(global, "v=1")
+		setVariable(global, "v=2")
+		if global.Variables["v"] != "2" || stderr.String() != "Warning: Overwriting variable 'v'\n" {
+			t.Errorf("v = %q, stderr = %q", global.Variables["v"], stderr.String())
+		}
+	})
+}
+
+func TestExpandVariables(t *testing.T) {
+	global, _, _ := newTestGlobal()
+	global.Variables = map[string]string{
+		"host":  "example.com",
+		"space": "  padded value \n",
+		"quote": "say \"hi\"\n",
+		"nul":   "a\x00b",
+	}
+
+	testCases := []struct {
+		input string
+		want  string
+	}{
+		{"https://{{host}}/path", "https://example.com/path"},
+		{"{{host}}{{host}}", "example.comexample.com"},
+		{"[{{space:trim}}]", "[padded value]"},
+		{"{{space:trim:url}}", "padded%20value"},
+		{"{{quote:json}}", `say \"hi\"\n`},
+		{"{{host:b64}}", "ZXhhbXBsZS5jb20="},
+		{"{{nul:b64}}", "YQBi"},
+		{"a{{unset}}b", "ab"},
+		{`\{{host}}`, "{{host}}"},
+		{"{{not a name}}", "{{not a name}}"},
+		{"{{host", "{{host"},
+		{"no braces", "no braces"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := expandVariables(global, tc.input)
+			if err != nil {
+				t.Fatalf("expandVariables() failed: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("expandVariables() = %q; want %q", got, tc.want)
+			}
+		})
+	}
+
+	for _, input := range []string{"{{host:upper}}", "{{nul}}", "{{nul:trim}}"} {
+		if _, err := expandVariables(global, input); !errors.Is(err, ParamExpandError) {
+			t.Errorf("expandVariables(%q) error = %v; want %v", input, err, ParamExpandError)
+		}
+	}
+}
+
+func TestParameterParser_Expand(t *testing.T) {
+	t.Setenv("CURL_TEST_TOKEN", "s3cr3t")
+	global := NewGlobalConfig()
+	args := []string{
+		"--variable", "%CURL_TEST_TOKEN",
+		"--variable", "host=example.com",
+		"--expand-header", "Authorization: Bearer {{CURL_TEST_TOKEN}}",
+		"--expand-url", "https://{{host}}/",
+		"--user-agent", "{{host}}",
+		"--expand-variable", "both={{host}}/{{CURL_TEST_TOKEN:b64}}",
+	}
+	if err := NewParameterParser(global).Parse(args); err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	config := global.Last
+	if len(config.Headers) != 1 || config.Headers[0] != "Authorization: Bearer s3cr3t" {
+		t.Errorf("Headers = %q", config.Headers)
+	}
+	if len(config.URLList) != 1 || config.URLList[0].URL != "https://example.com/" {
+		t.Errorf("URLList = %+v", config.URLList)
+	}
+	if config.UserAgent != "{{host}}" {
+		t.Errorf("UserAgent = %q; options without --expand- are not expanded", config.UserAgent)
+	}
+	if global.Variables["both"] != "example.com/czNjcjN0" {
+		t.Errorf("both = %q", global.Variables["both"])
+	}
+
+	for _, args := range [][]string{
+		{"--expand-verbose"},
+		{"--expand-url", "{{x:nope}}"},
+	} {
+		err := NewParameterParser(NewGlobalConfig()).Parse(args)
+		if !errors.Is(err, ParamExpandError) {
+			t.Errorf("Parse(%q) error = %v; want %v", args, err, ParamExpandError)
+		}
+	}
+}
@@ -1,9 +1,14 @@
 package tool
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // VariableType defines the type of a --write-out variable.
@@ -30,6 +35,8 @@ type WriteOutVariable struct {
 // curl-src/src/tool_writeout.c. A map is used for efficient lookups.
 var variables = map[string]WriteOutVariable{
 	"content_type":          {Name: "content_type", Type: VarTypeString},
+	"errormsg":              {Name: "errormsg", Type: VarTypeString},
+	"exitcode":              {Name: "exitcode", Type: VarTypeLong},
 	"filename_effective":    {Name: "filename_effective", Type: VarTypeString},
 	"ftp_entry_path":        {Name: "ftp_entry_path", Type: VarTypeString},
 	"http_code":             {Name: "http_code", Type: VarTypeLong},
@@ -63,15 +70,68 @@ var variables = map[string]WriteOutVariable{
 	"time_total":            {Name: "time_total", Type: VarTypeTime},
 	"url_effective":         {Name: "url_effective", Type: VarTypeString},
 	// Special variables that need custom handling
+	"header_json": {Name: "header_json", Type: VarTypeJSON},
 	"json":        {Name: "json", Type: VarTypeJSON},
+	"onerror":     {Name: "onerror", Type: VarTypeSpecial},
 	"stderr":      {Name: "stderr", Type: VarTypeSpecial},
 	"stdout":      {Name: "stdout", Type: VarTypeSpecial},
 }
 
+// urlParts are the parts of a URL given by the url.* variables, for the
+// URL as requested, and the urle.* ones, for the effective URL.
+var urlParts = []string{"scheme", "user", "password", "host", "port", "path", "query", "fragment", "zoneid"}
+
+func init() {
+	for _, part := range urlParts {
+		for _, prefix := range []string{"url.", "urle."} {
+			variables[prefix+part] = WriteOutVariable{Name: prefix + part, Type: VarTypeString}
+		}
+	}
+}
+
+// WriteOutData is what a --write-out format is rendered from.
+type WriteOutData struct {
+	// Info holds the variables collected during the transfer, keyed by
+	// their names in the `variables` map.
+	Info map[string]interface{}
+	// Headers holds the headers of the last response, for %{header_json}
+	// and %{header{name}}.
+	Headers http.Header
+	// URL is the URL as requested, which the url.* variables take apart.
+	// The urle.* ones use the url_effective variable.
+	URL string
+	// Now is the time %{time} shows; the current time when zero.
+	Now time.Time
+}
+
+// value returns the value of the variable name, and whether it has one.
+func (d *WriteOutData) value(name string) (interface{}, bool) {
+	if part, ok := strings.CutPrefix(name, "urle."); ok {
+		effective, _ := d.Info["url_effective"].(string)
+		return urlPart(effective, part)
+	}
+	if part, ok := strings.CutPrefix(name, "url."); ok {
+		return urlPart(d.URL, part)
+	}
+	val, ok := d.Info[name]
+	return val, ok
+}
+
 // WriteOut parses a format string and substitutes variables from the data map.
-// This is a translation of the C function `ourWriteOut` from
-// curl-src/src/tool_writeout.c.
+// Output that %{stderr} switches to stderr is written to writer as well.
 func WriteOut(writer io.Writer, format string, data map[string]interface{}) error {
+	return WriteOutTo(writer, writer, format, &WriteOutData{Info: data})
+}
+
+// WriteOutTo renders a --write-out format, starting on stdout. %{stderr}
+// and %{stdout} switch the output between the two writers, and
+// %{onerror} ends the output unless the transfer failed. Response headers
+// and the time are written with %{header{name}} and %{time{format}}, or
+// curl's %header{name} and %time{format}. This is a
+// translation of the C function `ourWriteOut` from
+// curl-src/src/tool_writeout.c.
+func WriteOutTo(stdout, stderr io.Writer, format string, data *WriteOutData) error {
+	writer := stdout
 	var i int
 	for i < len(format) {
 		char := format[i]
@@ -81,18 +141,51 @@ func WriteOut(writer io.Writer, format string, data map[string]interface{}) erro
 				// Escaped '%%'
 				fmt.Fprint(writer, "%")
 				i++
-			} else if format[i] == '{' {
-				// Variable substitution %{...}
-				i++ // Move past the '{'
+			} else if name, ok := writeOutFuncPrefix(format[i:]); ok {
+				// curl's %header{name} and %time{format}
+				i += len(name) + 1
 				end := strings.IndexByte(format[i:], '}')
 				if end == -1 {
 					return fmt.Errorf("unmatched brace in write-out format")
 				}
-				varName := format[i : i+end]
-				i += end + 1 // Move past the '}'
+				writeOutFunc(writer, name, format[i:i+end], data)
+				i += end + 1
+			} else if format[i] == '{' {
+				// Variable substitution %{...}, or %{header{name}} and
+				// %{time{format}} with an argument in nested braces.
+				i++ // Move past the '{'
+				varName, arg, hasArg, n, err := parseWriteOutVar(format[i:])
+				if err != nil {
+					return err
+				}
+				i += n
 
+				if hasArg || varName == "time" {
+					writeOutFunc(writer, varName, arg, data)
+					continue
+				}
 				if v, ok := variables[varName]; ok {
-					val, dataOk := data[v.Name]
+					switch v.Type {
+					case VarTypeSpecial:
+						switch v.Name {
+						case "stderr":
+							writer = stderr
+						case "stdout":
+							writer = stdout
+						case "onerror":
+							if code, _ := data.Info["exitcode"].(int64); code == int64(CurlOK) {
+								return nil
+							}
+						}
+						continue
+					case VarTypeJSON:
+						if err := writeOutJSONVar(writer, v.Name, data); err != nil {
+							return err
+						}
+						continue
+					}
+
+					val, dataOk := data.value(v.Name)
 					if !dataOk {
 						// In curl, this often prints 0 or an empty string.
 						// We'll print a default value based on type.
@@ -123,7 +216,6 @@ func WriteOut(writer io.Writer, format string, data map[string]interface{}) erro
 						} else {
 							fmt.Fprint(writer, "0.000000")
 						}
-					// TODO: Implement special cases like json, stderr, etc.
 					}
 				} else {
 					// Unknown variable, curl prints a warning to stderr
@@ -134,6 +226,20 @@ func WriteOut(writer io.Writer, format string, data map[string]interface{}) erro
 				fmt.Fprintf(writer, "%%%c", format[i])
 				i++
 			}
+		} else if char == '\\' && i+1 < len(format) {
+			// Backslash escapes, as handled by the C code.
+			switch format[i+1] {
+			case 'r':
+				fmt.Fprint(writer, "\r")
+			case 'n':
+				fmt.Fprint(writer, "\n")
+			case 't':
+				fmt.Fprint(writer, "\t")
+			default:
+				// Unknown escape, print both characters.
+				fmt.Fprintf(writer, "\\%c", format[i+1])
+			}
+			i += 2
 		} else {
 			// Regular character
 			fmt.Fprint(writer, string(char))
@@ -141,4 +247,214 @@ func WriteOut(writer io.Writer, format string, data map[string]interface{}) erro
 		}
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// writeOutJSONVar writes %{json} or %{header_json}. Like curl, the JSON is
+// not followed by a newline, so that a format such as '%{json}\n' gives one
+// object per line. %{json} lists every variable, null when it has no value.
+func writeOutJSONVar(writer io.Writer, name string, data *WriteOutData) error {
+	var buf bytes.Buffer
+	var err error
+	if name == "header_json" {
+		headers := data.Headers
+		if headers == nil {
+			headers = http.Header{}
+		}
+		err = HeaderJSON(&buf, headers)
+	} else {
+		info := make(map[string]interface{}, len(variables))
+		for k, val := range data.Info {
+			info[k] = val
+		}
+		for _, v := range variables {
+			if v.Type != VarTypeJSON && v.Type != VarTypeSpecial {
+				info[v.Name], _ = data.value(v.Name)
+			}
+		}
+		err = WriteOutJSON(&buf, info)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+	return err
+}
+
+// parseWriteOutVar parses the variable of a %{...}, s being what follows
+// the opening brace. It returns the name of the variable, the argument
+// given in nested braces as in %{header{name}}, and the length of s taken
+// up to the closing brace.
+func parseWriteOutVar(s string) (name, arg string, hasArg bool, n int, err error) {
+	end := strings.IndexByte(s, '}')
+	if end == -1 {
+		return "", "", false, 0, fmt.Errorf("unmatched brace in write-out format")
+	}
+	open := strings.IndexByte(s[:end], '{')
+	if open == -1 {
+		return s[:end], "", false, end + 1, nil
+	}
+	if end+1 >= len(s) || s[end+1] != '}' {
+		return "", "", false, 0, fmt.Errorf("unmatched brace in write-out format")
+	}
+	return s[:open], s[open+1 : end], true, end + 2, nil
+}
+
+// writeOutFuncPrefix reports whether s, what follows a '%', starts one of
+// curl's %header{name} or %time{format}, and returns the name.
+func writeOutFuncPrefix(s string) (string, bool) {
+	for _, name := range []string{"header", "time"} {
+		if strings.HasPrefix(s, name+"{") {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// writeOutFunc writes the variables that take an argument: the values of
+// the response header named arg, joined with ", " when it was sent more
+// than once, or the time formatted with the strftime format arg, in UTC.
+// An unknown name is ignored, like an unknown variable.
+func writeOutFunc(writer io.Writer, name, arg string, data *WriteOutData) {
+	switch name {
+	case "header":
+		fmt.Fprint(writer, strings.Join(data.Headers.Values(arg), ", "))
+	case "time":
+		now := data.Now
+		if now.IsZero() {
+			now = time.Now()
+		}
+		if arg == "" {
+			arg = "%Y-%m-%dT%H:%M:%SZ"
+		}
+		fmt.Fprint(writer, strftime(arg, now.UTC()))
+	}
+}
+
+// urlPart returns the part of rawURL named by a url.* variable, and
+// whether the URL has it. As in curl, the port is the scheme's default
+// one when the URL gives none, the path is at least "/", and an IPv6 host
+// keeps its brackets but not its zone, which is the zoneid part.
+func urlPart(rawURL, part string) (interface{}, bool) {
+	u, err := url.Parse(rawURL)
+	if err == nil && u.Scheme == "" {
+		u, err = url.Parse("http://" + rawURL)
+	}
+	if rawURL == "" || err != nil {
+		return nil, false
+	}
+	host, zone, _ := strings.Cut(u.Hostname(), "%")
+
+	var value string
+	switch part {
+	case "scheme":
+		value = u.Scheme
+	case "user":
+		if u.User != nil {
+			value = u.User.Username()
+		}
+	case "password":
+		if u.User != nil {
+			value, _ = u.User.Password()
+		}
+	case "host":
+		value = host
+		if strings.Contains(host, ":") {
+			value = "[" + host + "]"
+		}
+	case "port":
+		if value = u.Port(); value == "" {
+			value = defaultPorts[u.Scheme]
+		}
+	case "path":
+		if value = u.EscapedPath(); value == "" {
+			value = "/"
+		}
+	case "query":
+		value = u.RawQuery
+	case "fragment":
+		value = u.EscapedFragment()
+	case "zoneid":
+		value = zone
+	}
+	return value, value != ""
+}
+
+// strftime formats t with the conversions of the C function strftime,
+// plus curl's %f for the microseconds. Unknown conversions are kept as
+// they are.
+func strftime(format string, t time.Time) string {
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch c := format[i]; c {
+		case 'a':
+			b.WriteString(t.Format("Mon"))
+		case 'A':
+			b.WriteString(t.Format("Monday"))
+		case 'b', 'h':
+			b.WriteString(t.Format("Jan"))
+		case 'B':
+			b.WriteString(t.Format("January"))
+		case 'c':
+			b.WriteString(t.Format("Mon Jan  2 15:04:05 2006"))
+		case 'C':
+			fmt.Fprintf(&b, "%02d", t.Year()/100)
+		case 'd':
+			fmt.Fprintf(&b, "%02d", t.Day())
+		case 'D':
+			b.WriteString(t.Format("01/02/06"))
+		case 'e':
+			fmt.Fprintf(&b, "%2d", t.Day())
+		case 'f':
+			fmt.Fprintf(&b, "%06d", t.Nanosecond()/1000)
+		case 'F':
+			b.WriteString(t.Format("2006-01-02"))
+		case 'H':
+			fmt.Fprintf(&b, "%02d", t.Hour())
+		case 'I':
+			fmt.Fprintf(&b, "%02d", (t.Hour()+11)%12+1)
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'm':
+			fmt.Fprintf(&b, "%02d", int(t.Month()))
+		case 'M':
+			fmt.Fprintf(&b, "%02d", t.Minute())
+		case 'n':
+			b.WriteByte('\n')
+		case 'p':
+			b.WriteString(t.Format("PM"))
+		case 'R':
+			b.WriteString(t.Format("15:04"))
+		case 's':
+			b.WriteString(strconv.FormatInt(t.Unix(), 10))
+		case 'S':
+			fmt.Fprintf(&b, "%02d", t.Second())
+		case 't':
+			b.WriteByte('\t')
+		case 'T':
+			b.WriteString(t.Format("15:04:05"))
+		case 'u':
+			fmt.Fprintf(&b, "%d", (int(t.Weekday())+6)%7+1)
+		case 'w':
+			fmt.Fprintf(&b, "%d", int(t.Weekday()))
+		case 'y':
+			fmt.Fprintf(&b, "%02d", t.Year()%100)
+		case 'Y':
+			fmt.Fprintf(&b, "%d", t.Year())
+		case 'z':
+			b.WriteString(t.Format("-0700"))
+		case 'Z':
+			b.WriteString(t.Format("MST"))
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
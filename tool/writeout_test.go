@@ -1,8 +1,11 @@
 package tool
 
 import (
+	"encoding/json"
+	"net/http"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestWriteOut(t *testing.T) {
@@ -63,6 +66,12 @@ func TestWriteOut(t *testing.T) {
 			data:     sampleData, // time_connect is not in the map
 			expected: "Connect time: 0.000000",
 		},
+		{
+			name:     "backslash escapes",
+			format:   `%{http_code}\n\t\x`,
+			data:     sampleData,
+			expected: "200\n\t\\x",
+		},
 		{
 			name:     "no variables",
 			format:   "Just a plain string.",
@@ -93,4 +102,104 @@ func TestWriteOut(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestWriteOutTo(t *testing.T) {
+	ok := &WriteOutData{
+		Info:    map[string]interface{}{"http_code": int64(200), "exitcode": int64(0)},
+		Headers: http.Header{"Content-Type": {"text/plain"}},
+	}
+	full := &WriteOutData{
+		Info: map[string]interface{}{
+			"url_effective": "https://example.com/next?b=2",
+		},
+		Headers: http.Header{"Content-Type": {"text/html"}, "Set-Cookie": {"a=1", "b=2"}},
+		URL:     "http://user:secret@[fe80::1%25eth0]:8080/path/to?a=1#top",
+		Now:     time.Date(2024, 3, 5, 7, 8, 9, 123456000, time.UTC),
+	}
+	failed := &WriteOutData{
+		Info: map[string]interface{}{"exitcode": int64(7), "errormsg": "Failed to connect"},
+	}
+
+	testCases := []struct {
+		name       string
+		format     string
+		data       *WriteOutData
+		wantStdout string
+		wantStderr string
+	}{
+		{"stderr and back", "a%{stderr}b%{stdout}c", ok, "ac", "b"},
+		{"onerror on success", "%{http_code}%{onerror} failed", ok, "200", ""},
+		{"onerror on failure", "%{onerror}%{stderr}error %{exitcode}: %{errormsg}", failed, "", "error 7: Failed to connect"},
+		{"header_json", "%{header_json}\\n", ok, `{"Content-Type":["text/plain"]}` + "\n", ""},
+		{"header_json without headers", "%{header_json}", failed, "{}", ""},
+		{"header", "%{header{content-type}} %header{set-cookie}|%{header{missing}}|", full, "text/html a=1, b=2||", ""},
+		{"url parts", "%{url.scheme} %{url.user}:%{url.password} %{url.host} %{url.zoneid} %{url.port} %{url.path} %{url.query} %{url.fragment}",
+			full, "http user:secret [fe80::1] eth0 8080 /path/to a=1 top", ""},
+		{"effective url parts", "%{urle.host}:%{urle.port}%{urle.path}?%{urle.query}|%{urle.user}|", full, "example.com:443/next?b=2||", ""},
+		{"time", "%{time} %{time{%d/%m/%Y %H:%M:%S.%f}} %time{%s}", full,
+			"2024-03-05T07:08:09Z 05/03/2024 07:08:09.123456 1709622489", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			if err := WriteOutTo(&stdout, &stderr, tc.format, tc.data); err != nil {
+				t.Fatalf("WriteOutTo() failed: %v", err)
+			}
+			if stdout.String() != tc.wantStdout || stderr.String() != tc.wantStderr {
+				t.Errorf("WriteOutTo(%q) = %q, %q; want %q, %q", tc.format,
+					stdout.String(), stderr.String(), tc.wantStdout, tc.wantStderr)
+			}
+		})
+	}
+
+	t.Run("unmatched nested brace", func(t *testing.T) {
+		var stdout strings.Builder
+		if err := WriteOutTo(&stdout, &stdout, "%{header{content-type}", full); err == nil {
+			t.Errorf("WriteOutTo() should fail")
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var stdout strings.Builder
+		if err := WriteOutTo(&stdout, &stdout, "%{json}\\n", ok); err != nil {
+			t.Fatalf("WriteOutTo() failed: %v", err)
+		}
+		line, rest, _ := strings.Cut(stdout.String(), "\n")
+		if rest != "" {
+			t.Errorf("output = %q; want a single line", stdout.String())
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("invalid JSON %q: %v", line, err)
+		}
+		if got["http_code"] != float64(200) {
+			t.Errorf("http_code = %v; want 200", got["http_code"])
+		}
+		if v, found := got["remote_ip"]; !found || v != nil {
+			t.Errorf("remote_ip = %v, %v; want null", v, found)
+		}
+		if _, found := got["stderr"]; found {
+			t.Errorf("JSON lists the special variable stderr")
+		}
+	})
+}
+
+func TestStrftime(t *testing.T) {
+	tm := time.Date(2024, 3, 5, 17, 8, 9, 0, time.UTC)
+	testCases := []struct {
+		format string
+		want   string
+	}{
+		{"%Y-%m-%d", "2024-03-05"},
+		{"%a %b %e %T %Z", "Tue Mar  5 17:08:09 UTC"},
+		{"%I:%M %p", "05:08 PM"},
+		{"%j %u %w %y %C", "065 2 2 24 20"},
+		{"100%% %q", "100% %q"},
+	}
+	for _, tc := range testCases {
+		if got := strftime(tc.format, tm); got != tc.want {
+			t.Errorf("strftime(%q) = %q; want %q", tc.format, got, tc.want)
+		}
+	}
+}